@@ -0,0 +1,207 @@
+package goson
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"unicode/utf8"
+)
+
+// Built-in character class names usable in patterns: <8:hex>, <2-4:upper/3:digit>.
+const (
+	classLower     = "lower"
+	classUpper     = "upper"
+	classLetter    = "letter"
+	classDigit     = "digit"
+	classSpecial   = "special"
+	classAlnum     = "alnum"
+	classHex       = "hex"
+	classBase32    = "base32"
+	classCyrillic  = "cyrillic"
+	classGreek     = "greek"
+	classCJK       = "cjk"
+	classEmoji     = "emoji"
+	classCombining = "combining"
+)
+
+var builtinClasses = map[string][]rune{
+	classLower:     []rune("abcdefghijklmnopqrstuvwxyz"),
+	classUpper:     []rune("ABCDEFGHIJKLMNOPQRSTUVWXYZ"),
+	classLetter:    []rune(bytePoolLetters),
+	classDigit:     []rune(bytePoolDigits),
+	classSpecial:   []rune(bytePoolSpecials),
+	classAlnum:     []rune(bytePoolLetters + bytePoolDigits),
+	classHex:       []rune("0123456789abcdef"),
+	classBase32:    []rune("ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"),
+	classCyrillic:  append(runeRange('А', 'я'), 'Ё', 'ё'),
+	classGreek:     append(append(runeRange('Α', 'Ρ'), runeRange('Σ', 'Ω')...), runeRange('α', 'ω')...),
+	classCJK:       runeRange(0x4e00, 0x9fff),
+	classEmoji:     runeRange(0x1f600, 0x1f64f),
+	classCombining: runeRange(0x0300, 0x036f),
+}
+
+// runeRange returns runes within [from, to]
+func runeRange(from, to rune) []rune {
+	runes := make([]rune, 0, to-from+1)
+	for char := from; char <= to; char++ {
+		runes = append(runes, char)
+	}
+
+	return runes
+}
+
+// positionalClasses are used by the classic <letters/digits/specials> form,
+// where a part has no class name and its meaning depends on its position.
+var positionalClasses = []string{classLetter, classDigit, classSpecial}
+
+var (
+	poolsMu sync.RWMutex
+	pools   = make(map[string][]rune)
+)
+
+// RegisterPool registers a project-wide character pool,
+// so patterns can reference it by name: <6:vowels>.
+// Pools are resolved when a template is parsed,
+// so registering a pool doesn't affect already created processors.
+// Built-in class names can't be overridden.
+// A pool may contain any Unicode characters: RegisterPool("kana", "あいうえお").
+func RegisterPool(name, chars string) error {
+	if !isValidPoolName(name) {
+		return fmt.Errorf("invalid pool name: %q", name)
+	}
+
+	if _, ok := builtinClasses[name]; ok {
+		return fmt.Errorf("pool %s is built-in and can't be overridden", name)
+	}
+
+	pool, err := normalizePool(chars)
+	if err != nil {
+		return fmt.Errorf("pool %s: %w", name, err)
+	}
+
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+	pools[name] = pool
+
+	return nil
+}
+
+// lookupPool returns characters of a built-in class or a registered pool
+func lookupPool(name string) ([]rune, bool) {
+	if pool, ok := builtinClasses[name]; ok {
+		return pool, true
+	}
+
+	poolsMu.RLock()
+	defer poolsMu.RUnlock()
+	pool, ok := pools[name]
+
+	return pool, ok
+}
+
+func isValidPoolName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for i, char := range name {
+		switch {
+		case char == '_', char >= 'a' && char <= 'z', char >= 'A' && char <= 'Z':
+		case i > 0 && char >= '0' && char <= '9':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// normalizePool drops repeated characters, so every character has the same chance to be picked
+func normalizePool(chars string) ([]rune, error) {
+	if chars == "" {
+		return nil, errors.New("pool is empty")
+	}
+
+	if !utf8.ValidString(chars) {
+		return nil, errors.New("pool is not a valid UTF-8 string")
+	}
+
+	seen := make(map[rune]bool, len(chars))
+	pool := make([]rune, 0, len(chars))
+	for _, char := range chars {
+		if seen[char] {
+			continue
+		}
+
+		seen[char] = true
+		pool = append(pool, char)
+	}
+
+	return pool, nil
+}
+
+const (
+	classSetOpen  = '['
+	classSetClose = ']'
+)
+
+// parseClass resolves a class reference of a pattern part:
+// a built-in class, a registered pool or a custom set like [A-F0-9]
+func parseClass(raw string) ([]rune, error) {
+	if len(raw) > 0 && raw[0] == classSetOpen {
+		return parseClassSet(raw)
+	}
+
+	pool, ok := lookupPool(raw)
+	if !ok {
+		return nil, fmt.Errorf("unknown character class: %s", raw)
+	}
+
+	return pool, nil
+}
+
+// parseClassSet expands a custom set: [A-F0-9_] or [а-яё].
+// A backslash escapes the next character, so [\]\-] is a set of "]" and "-".
+func parseClassSet(raw string) ([]rune, error) {
+	if len(raw) < 2 || raw[len(raw)-1] != classSetClose {
+		return nil, fmt.Errorf("unterminated character set: %s", raw)
+	}
+
+	body := []rune(raw[1 : len(raw)-1])
+	chars := make([]rune, 0, len(body))
+	escaped := make([]bool, 0, len(body))
+	for i := 0; i < len(body); i++ {
+		if body[i] == '\\' && i+1 < len(body) {
+			i++
+			chars = append(chars, body[i])
+			escaped = append(escaped, true)
+			continue
+		}
+
+		chars = append(chars, body[i])
+		escaped = append(escaped, false)
+	}
+
+	expanded := make([]rune, 0, len(chars))
+	for i := 0; i < len(chars); i++ {
+		if i+2 < len(chars) && chars[i+1] == '-' && !escaped[i+1] {
+			from, to := chars[i], chars[i+2]
+			if from > to {
+				return nil, fmt.Errorf("invalid character range: %c-%c", from, to)
+			}
+
+			expanded = append(expanded, runeRange(from, to)...)
+			i += 2
+			continue
+		}
+
+		expanded = append(expanded, chars[i])
+	}
+
+	pool, err := normalizePool(string(expanded))
+	if err != nil {
+		return nil, fmt.Errorf("character set %s: %w", raw, err)
+	}
+
+	return pool, nil
+}
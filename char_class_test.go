@@ -0,0 +1,191 @@
+package goson
+
+import (
+	"regexp"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterPool(t *testing.T) {
+	tests := []struct {
+		name    string
+		pool    string
+		chars   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "positive",
+			pool:  "vowels",
+			chars: "aeiou",
+			want:  "aeiou",
+		},
+		{
+			name:  "positive repeated characters",
+			pool:  "binary",
+			chars: "0101",
+			want:  "01",
+		},
+		{
+			name:    "built-in class",
+			pool:    "hex",
+			chars:   "abc",
+			wantErr: true,
+		},
+		{
+			name:    "invalid name",
+			pool:    "my-pool",
+			chars:   "abc",
+			wantErr: true,
+		},
+		{
+			name:    "empty pool",
+			pool:    "empty",
+			chars:   "",
+			wantErr: true,
+		},
+		{
+			name:  "positive unicode pool",
+			pool:  "kana",
+			chars: "あいうあ",
+			want:  "あいう",
+		},
+		{
+			name:    "invalid UTF-8",
+			pool:    "broken",
+			chars:   "a\xffb",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RegisterPool(tt.pool, tt.chars)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			got, ok := lookupPool(tt.pool)
+			require.True(t, ok)
+			assert.Equal(t, []rune(tt.want), got)
+		})
+	}
+}
+
+func Test_parseClassSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "positive ranges",
+			input: "[a-c0-2]",
+			want:  "abc012",
+		},
+		{
+			name:  "positive trailing dash",
+			input: "[ab-]",
+			want:  "ab-",
+		},
+		{
+			name:  "positive escaped characters",
+			input: `[\]\-x]`,
+			want:  "]-x",
+		},
+		{
+			name:  "positive unicode range",
+			input: "[а-дё]",
+			want:  "абвгдё",
+		},
+		{
+			name:    "reversed range",
+			input:   "[z-a]",
+			wantErr: true,
+		},
+		{
+			name:    "empty set",
+			input:   "[]",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseClassSet(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, []rune(tt.want), got)
+		})
+	}
+}
+
+func Test_newPatternGenerator_classes(t *testing.T) {
+	require.NoError(t, RegisterPool("xyz", "xyz"))
+
+	testGenerator, err := newPatternGenerator("tok_<8:hex/2:xyz>")
+	require.NoError(t, err)
+
+	rnd := newTestRand()
+	for i := 0; i < 50; i++ {
+		got := testGenerator.Generate(rnd)
+		assert.Regexp(t, "^tok_[0-9a-fxyz]{10}$", got)
+		assert.Len(t, regexp.MustCompile("[xyz]").FindAllString(got, -1), 2)
+	}
+}
+
+func Test_newPatternGenerator_unicode(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		regexp string
+		runes  int
+	}{
+		{
+			name:   "cyrillic prefix",
+			input:  "Привет <4:cyrillic/2:digit>",
+			regexp: "^Привет [а-яА-ЯёЁ0-9]{6}$",
+			runes:  13,
+		},
+		{
+			name:   "cjk and emoji",
+			input:  "<3:cjk><2:emoji>",
+			regexp: "^\\p{Han}{3}[\\x{1F600}-\\x{1F64F}]{2}$",
+			runes:  5,
+		},
+		{
+			name:   "combining marks",
+			input:  "e<1:combining>",
+			regexp: "^e\\p{Mn}$",
+			runes:  2,
+		},
+		{
+			name:   "escaped multibyte suffix",
+			input:  "<2:[ж-й]> \\<ok\\> ✓",
+			regexp: "^[ж-й]{2} <ok> ✓$",
+			runes:  9,
+		},
+	}
+	rnd := newTestRand()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testGenerator, err := newPatternGenerator(tt.input)
+			require.NoError(t, err)
+
+			for i := 0; i < 20; i++ {
+				got := testGenerator.Generate(rnd)
+				assert.True(t, utf8.ValidString(got))
+				assert.Equal(t, tt.runes, utf8.RuneCountInString(got))
+				assert.Regexp(t, tt.regexp, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,218 @@
+package goson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// oneOfField picks one of the listed values, uniformly or according to their weights
+type oneOfField struct {
+	name       string
+	choices    []interface{}
+	encoded    [][]byte  // choices encoded as JSON
+	cumulative []float64 // cumulative weights, nil for a uniform choice
+}
+
+func (oneOfField *oneOfField) Name() string {
+	return oneOfField.name
+}
+
+func (oneOfField *oneOfField) Value() interface{} {
+	return oneOfField.valueWith(fallbackRand)
+}
+
+func (oneOfField *oneOfField) valueWith(rnd *rand.Rand) interface{} {
+	return oneOfField.choices[oneOfField.pick(rnd)]
+}
+
+func (oneOfField *oneOfField) appendJSON(enc *encoder) {
+	enc.buf = append(enc.buf, oneOfField.encoded[oneOfField.pick(enc.rnd)]...)
+}
+
+// pick returns an index of a choice
+func (oneOfField *oneOfField) pick(rnd *rand.Rand) int {
+	if oneOfField.cumulative == nil {
+		return rnd.Intn(len(oneOfField.choices))
+	}
+
+	total := oneOfField.cumulative[len(oneOfField.cumulative)-1]
+	point := rnd.Float64() * total
+	if point >= total {
+		// rounding, the point must stay below the total to land on a value with a positive weight
+		point = math.Nextafter(total, 0)
+	}
+
+	return sort.Search(len(oneOfField.cumulative), func(i int) bool {
+		return oneOfField.cumulative[i] > point
+	})
+}
+
+// weight returns a weight of a choice, every choice weighs 1 in a uniform choice
+func (oneOfField *oneOfField) weight(i int) float64 {
+	switch {
+	case oneOfField.cumulative == nil:
+		return 1
+	case i == 0:
+		return oneOfField.cumulative[0]
+	default:
+		return oneOfField.cumulative[i] - oneOfField.cumulative[i-1]
+	}
+}
+
+// weightsKeyword is a trailing argument of oneof listing weights of its values
+const weightsKeyword = "weights"
+
+// newOneOfField handles "oneof" keyword: "_go:oneof(\"active\", \"pending\", \"banned\")".
+// Values are any JSON values: strings, numbers, booleans, null, arrays and objects, objects keep the order of their keys.
+// An optional trailing bare weights(...) makes the choice weighted, a quoted "weights(...)" is a string value:
+// "_go:oneof(active, banned, weights(9, 1))" gives active in 90% of records
+func newOneOfField(name string, call *KeywordCall) (ParsedField, error) {
+	if err := call.Arity(1, -1); err != nil {
+		return nil, err
+	}
+
+	n := len(call.Args)
+	var weights []float64
+	if last := call.rawArg(n - 1); isWeights(last) {
+		n--
+
+		var err error
+		if weights, err = parseWeights(last, n); err != nil {
+			return nil, fmt.Errorf("keyword %s: %v", call.Keyword, err)
+		}
+	}
+
+	if n == 0 {
+		return nil, fmt.Errorf("keyword %s expects at least one value", call.Keyword)
+	}
+
+	field := &oneOfField{
+		name:    name,
+		choices: make([]interface{}, n),
+		encoded: make([][]byte, n),
+	}
+	for i := 0; i < n; i++ {
+		choice, err := orderedArg(call, i)
+		if err != nil {
+			return nil, fmt.Errorf("keyword %s: argument %d: %v", call.Keyword, i+1, err)
+		}
+
+		encoded, err := json.Marshal(choice)
+		if err != nil {
+			return nil, fmt.Errorf("keyword %s: argument %d: %v", call.Keyword, i+1, err)
+		}
+		field.choices[i], field.encoded[i] = choice, encoded
+	}
+
+	if weights != nil {
+		field.cumulative = make([]float64, len(weights))
+		var total float64
+		for i, weight := range weights {
+			total += weight
+			field.cumulative[i] = total
+		}
+	}
+
+	return field, nil
+}
+
+// isWeights reports whether a raw argument is a weights(...) call rather than a value,
+// a quoted "weights(1)" is a string value
+func isWeights(rawArg string) bool {
+	return strings.HasPrefix(rawArg, weightsKeyword+string(keywordArgsOpen))
+}
+
+// orderedArg returns the i-th argument of a call, objects are decoded from the raw argument in the order of their keys
+func orderedArg(call *KeywordCall, i int) (interface{}, error) {
+	raw := call.rawArg(i)
+	if !strings.HasPrefix(raw, "{") && !strings.HasPrefix(raw, "[") {
+		return call.Args[i], nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.UseNumber()
+
+	return decodeOrdered(decoder)
+}
+
+// decodeOrdered decodes a JSON value with objects decoded into orderedObjects
+func decodeOrdered(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		object := newOrderedObject(0)
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object.set(key.(string), value)
+		}
+
+		_, err := decoder.Token()
+
+		return object, err
+	case json.Delim('['):
+		items := make([]interface{}, 0)
+		for decoder.More() {
+			item, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+
+		_, err := decoder.Token()
+
+		return items, err
+	}
+
+	return token, nil
+}
+
+// parseWeights parses a weights(...) argument, there must be a non-negative weight for every value
+func parseWeights(raw string, n int) ([]float64, error) {
+	call, err := parseKeywordCall(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid weights: %s", raw)
+	}
+
+	if len(call.Args) != n {
+		return nil, fmt.Errorf("%d weights for %d values", len(call.Args), n)
+	}
+
+	weights := make([]float64, n)
+	var total float64
+	for i := range call.Args {
+		weight, err := call.Float(i)
+		if err != nil {
+			return nil, err
+		}
+
+		if weight < 0 {
+			return nil, fmt.Errorf("weight %d is negative: %v", i+1, weight)
+		}
+		weights[i] = weight
+		total += weight
+	}
+
+	if total == 0 {
+		return nil, errors.New("weights must not all be zero")
+	}
+
+	return weights, nil
+}
@@ -0,0 +1,176 @@
+package goson
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newOneOfField(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    *oneOfField
+		wantErr bool
+	}{
+		{
+			name:  "strings",
+			input: `oneof("active", "pending", "banned")`,
+			want: &oneOfField{
+				name:    "status",
+				choices: []interface{}{"active", "pending", "banned"},
+				encoded: [][]byte{[]byte(`"active"`), []byte(`"pending"`), []byte(`"banned"`)},
+			},
+		},
+		{
+			name:  "short form",
+			input: "oneof:usd:eur",
+			want: &oneOfField{
+				name:    "status",
+				choices: []interface{}{"usd", "eur"},
+				encoded: [][]byte{[]byte(`"usd"`), []byte(`"eur"`)},
+			},
+		},
+		{
+			name:  "mixed types",
+			input: `oneof(1.50, true, null, {"plan": "pro", "b": [{"y": 1, "x": 2}]}, [1, 2], weights)`,
+			want: &oneOfField{
+				name: "status",
+				choices: []interface{}{
+					json.Number("1.50"), true, nil,
+					&orderedObject{
+						keys: []string{"plan", "b"},
+						values: []interface{}{"pro", []interface{}{
+							&orderedObject{keys: []string{"y", "x"}, values: []interface{}{json.Number("1"), json.Number("2")}},
+						}},
+					},
+					[]interface{}{json.Number("1"), json.Number("2")}, "weights",
+				},
+				encoded: [][]byte{
+					[]byte(`1.50`), []byte(`true`), []byte(`null`), []byte(`{"plan":"pro","b":[{"y":1,"x":2}]}`),
+					[]byte(`[1,2]`), []byte(`"weights"`),
+				},
+			},
+		},
+		{
+			name:  "quoted weights",
+			input: `oneof(a, "weights(1)")`,
+			want: &oneOfField{
+				name:    "status",
+				choices: []interface{}{"a", "weights(1)"},
+				encoded: [][]byte{[]byte(`"a"`), []byte(`"weights(1)"`)},
+			},
+		},
+		{
+			name:  "weighted",
+			input: `oneof("active", "banned", weights(9, 1))`,
+			want: &oneOfField{
+				name:       "status",
+				choices:    []interface{}{"active", "banned"},
+				encoded:    [][]byte{[]byte(`"active"`), []byte(`"banned"`)},
+				cumulative: []float64{9, 10},
+			},
+		},
+		{
+			name:    "no values",
+			input:   "oneof()",
+			wantErr: true,
+		},
+		{
+			name:    "only weights",
+			input:   "oneof(weights(1))",
+			wantErr: true,
+		},
+		{
+			name:    "weights count",
+			input:   "oneof(a, b, weights(1))",
+			wantErr: true,
+		},
+		{
+			name:    "negative weight",
+			input:   "oneof(a, b, weights(1, -1))",
+			wantErr: true,
+		},
+		{
+			name:    "zero weights",
+			input:   "oneof(a, b, weights(0, 0))",
+			wantErr: true,
+		},
+		{
+			name:    "not a number weight",
+			input:   "oneof(a, b, weights(1, x))",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call, err := parseKeywordCall(tt.input)
+			require.NoError(t, err)
+
+			got, err := newOneOfField("status", call)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_oneOfField_pick(t *testing.T) {
+	call, err := parseKeywordCall(`oneof(a, b, c, d, weights(0.9, 0, 0.1, 0))`)
+	require.NoError(t, err)
+	field, err := newOneOfField("status", call)
+	require.NoError(t, err)
+
+	const n = 10000
+	rnd := rand.New(rand.NewSource(1))
+	counts := make(map[interface{}]int)
+	for i := 0; i < n; i++ {
+		counts[field.(*oneOfField).valueWith(rnd)]++
+	}
+
+	assert.InDelta(t, 0.9, float64(counts["a"])/n, 0.02)
+	assert.InDelta(t, 0.1, float64(counts["c"])/n, 0.02)
+	assert.Zero(t, counts["b"])
+	assert.Zero(t, counts["d"])
+}
+
+func Test_processor_Generate_oneof(t *testing.T) {
+	body := []byte(`
+		{
+			"status": "_go:oneof(active, pending, banned, weights(90, 5, 5))",
+			"currency": "_go:oneof:usd:eur:gbp",
+			"plan": "_go:oneof({\"seats\": 5, \"name\": \"pro\"}, {\"name\": \"free\"}, null)",
+			"label": "_go:${oneof(\"<b>\", \"&\")}-<2:digit>"
+		}
+	`)
+
+	compiled, err := New(body, WithSeed(6))
+	require.NoError(t, err)
+	require.NotNil(t, compiled.(*processor).program)
+
+	reference, err := New(body, WithSeed(6))
+	require.NoError(t, err)
+	reference.(*processor).program = nil
+
+	for i := 0; i < 100; i++ {
+		got := compiled.Generate()
+		assert.Equal(t, string(reference.Generate()), string(got))
+
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(got, &record))
+		assert.Contains(t, []interface{}{"active", "pending", "banned"}, record["status"])
+		assert.Contains(t, []interface{}{"usd", "eur", "gbp"}, record["currency"])
+		assert.Contains(t, []interface{}{
+			map[string]interface{}{"name": "pro", "seats": float64(5)}, map[string]interface{}{"name": "free"}, nil,
+		}, record["plan"])
+		assert.Regexp(t, `^(<b>|&)-\d{2}$`, record["label"])
+		assert.Regexp(t, `"plan":(\{"seats":5,"name":"pro"\}|\{"name":"free"\}|null)`, string(got))
+	}
+}
@@ -0,0 +1,90 @@
+package goson
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Clock is a source of the current time for time-based keywords: timestamp and uuid:v7.
+// It must be safe for a concurrent use
+type Clock interface {
+	Now() time.Time
+}
+
+// recordClock is a Clock that is notified when a record is generated
+type recordClock interface {
+	Clock
+	advance()
+}
+
+// timedField is a field that reads the current time from a Clock
+type timedField interface {
+	useClock(clock Clock)
+}
+
+// clockNow returns the time of a clock, nil stands for the system one
+func clockNow(clock Clock) time.Time {
+	if clock == nil {
+		return time.Now()
+	}
+
+	return clock.Now()
+}
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (fixedClock *fixedClock) Now() time.Time {
+	return fixedClock.now
+}
+
+// NewFixedClock returns a Clock that always returns now
+func NewFixedClock(now time.Time) Clock {
+	return &fixedClock{
+		now: now,
+	}
+}
+
+type offsetClock struct {
+	offset time.Duration
+}
+
+func (offsetClock *offsetClock) Now() time.Time {
+	return time.Now().Add(offsetClock.offset)
+}
+
+// NewOffsetClock returns a Clock that runs as the system one shifted by offset,
+// e.g. NewOffsetClock(-365 * 24 * time.Hour) generates a dataset of the last year
+func NewOffsetClock(offset time.Duration) Clock {
+	return &offsetClock{
+		offset: offset,
+	}
+}
+
+type advancingClock struct {
+	// ticks is a number of generated records. It goes first:
+	// 64-bit atomic operations need 8-byte alignment that 32-bit platforms guarantee only for the first word
+	ticks int64
+	start time.Time
+	step  time.Duration
+}
+
+func (advancingClock *advancingClock) Now() time.Time {
+	return advancingClock.start.Add(time.Duration(atomic.LoadInt64(&advancingClock.ticks)) * advancingClock.step)
+}
+
+func (advancingClock *advancingClock) advance() {
+	atomic.AddInt64(&advancingClock.ticks, 1)
+}
+
+// NewAdvancingClock returns a Clock that starts at start and steps forward by step after every generated record,
+// so fields of a record share the same time and records form a time series.
+// Records generated concurrently, by GenerateParallel or concurrent calls of Generate,
+// step the clock in the order they are completed, so a record may see the steps of the others
+func NewAdvancingClock(start time.Time, step time.Duration) Clock {
+	return &advancingClock{
+		start: start,
+		step:  step,
+	}
+}
@@ -0,0 +1,111 @@
+package goson
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithClock_fixed(t *testing.T) {
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	body := []byte(`
+		{
+			"s": "_go:timestamp",
+			"ms": "_go:timestamp(ms)",
+			"at": "_go:timestamp(rfc3339)",
+			"local": "_go:timestamp(datetime, +03:00)",
+			"day": "_go:timestamp(date, -1d, -1d)",
+			"note": "_go:at ${timestamp(kitchen)}"
+		}
+	`)
+
+	testProcessor, err := New(body, WithClock(NewFixedClock(now)))
+	require.NoError(t, err)
+
+	expect := `{"s":1614834367,"ms":1614834367000,"at":"2021-03-04T05:06:07Z",` +
+		`"local":"2021-03-04 08:06:07","day":"2021-03-03","note":"at 5:06AM"}`
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, expect, string(testProcessor.Generate()))
+	}
+}
+
+func TestWithClock_offset(t *testing.T) {
+	testProcessor, err := New([]byte(`{"s": "_go:timestamp"}`), WithClock(NewOffsetClock(-24*time.Hour)))
+	require.NoError(t, err)
+
+	var record struct {
+		S int64 `json:"s"`
+	}
+	require.NoError(t, json.Unmarshal(testProcessor.Generate(), &record))
+	assert.InDelta(t, time.Now().Add(-24*time.Hour).Unix(), record.S, 2)
+}
+
+func TestWithClock_advancing(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	bodies := map[string][]byte{
+		"compiled":   []byte(`{"at": "_go:timestamp(rfc3339)", "id": "_go:uuid:v7", "copy": "_go:timestamp(rfc3339)"}`),
+		"references": []byte(`{"at": "_go:timestamp(rfc3339)", "id": "_go:uuid:v7", "copy": "_go:_at"}`),
+	}
+	for name, body := range bodies {
+		t.Run(name, func(t *testing.T) {
+			testProcessor, err := New(body, WithClock(NewAdvancingClock(start, time.Minute)))
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, testProcessor.GenerateTo(context.Background(), &buf, 3))
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			require.Len(t, lines, 3)
+			for i, line := range lines {
+				var record map[string]string
+				require.NoError(t, json.Unmarshal([]byte(line), &record))
+
+				at := start.Add(time.Duration(i) * time.Minute)
+				assert.Equal(t, at.Format(time.RFC3339), record["at"])
+				assert.Equal(t, record["at"], record["copy"])
+
+				millis, err := strconv.ParseInt(strings.ReplaceAll(record["id"], "-", "")[:12], 16, 64)
+				require.NoError(t, err)
+				assert.Equal(t, at.UnixMilli(), millis)
+			}
+
+			next := string(testProcessor.Generate())
+			assert.Contains(t, next, start.Add(3*time.Minute).Format(time.RFC3339))
+		})
+	}
+}
+
+func TestNewAdvancingClock_alignment(t *testing.T) {
+	// atomic operations on ticks panic on 32-bit platforms unless it is 8-byte aligned
+	assert.Zero(t, unsafe.Offsetof(advancingClock{}.ticks)%8)
+}
+
+func TestNewFixedClock_uuidV7(t *testing.T) {
+	now := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	testProcessor, err := New([]byte(`{"id": "_go:uuid:v7"}`), WithClock(NewFixedClock(now)), WithSeed(1))
+	require.NoError(t, err)
+
+	var previous string
+	for i := 0; i < 10; i++ {
+		var record map[string]string
+		require.NoError(t, json.Unmarshal(testProcessor.Generate(), &record))
+
+		raw, err := hex.DecodeString(strings.ReplaceAll(record["id"], "-", ""))
+		require.NoError(t, err)
+		millis, err := strconv.ParseInt(hex.EncodeToString(raw[:6]), 16, 64)
+		require.NoError(t, err)
+
+		assert.Equal(t, now.UnixMilli(), millis)
+		assert.Greater(t, record["id"], previous)
+		previous = record["id"]
+	}
+}
@@ -0,0 +1,374 @@
+package goson
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// program is a template compiled into a flat list of instructions.
+// It appends a record as JSON straight into a buffer,
+// without building interface{} values and without reflection
+type program struct {
+	instructions []instruction
+	slots        int // a number of referenced values captured by the program and its nested programs
+}
+
+// instruction writes a literal, appends a field value, repeats a nested program or runs it with a probability.
+// Values of references are copied from the captured slots when the whole record is written
+type instruction struct {
+	literal   []byte         // JSON written as is: keys, punctuation and static values
+	separator bool           // a comma before an entry that follows optional entries only
+	field     ParsedField    // a field to append
+	repeat    *repeatField   // a repeat field whose items are written with body
+	optional  *optionalField // an optional field whose entry or value is written with body
+	slot      *slot          // a referenced value written with body and captured into a slot
+	reference *reference     // a place of a reference value
+	body      *program
+}
+
+// run appends a record into enc.buf
+func (program *program) run(enc *encoder) {
+	for i := range program.instructions {
+		instruction := &program.instructions[i]
+		switch {
+		case instruction.literal != nil:
+			enc.buf = append(enc.buf, instruction.literal...)
+		case instruction.separator:
+			// nothing is written yet if the object has just been opened
+			if enc.buf[len(enc.buf)-1] != '{' {
+				enc.buf = append(enc.buf, ',')
+			}
+		case instruction.repeat != nil:
+			n := instruction.repeat.count(enc.rnd)
+			enc.buf = append(enc.buf, '[')
+			for j := 0; j < n; j++ {
+				if j > 0 {
+					enc.buf = append(enc.buf, ',')
+				}
+				instruction.body.run(enc)
+			}
+			enc.buf = append(enc.buf, ']')
+		case instruction.optional != nil:
+			switch {
+			case instruction.optional.present(enc.rnd):
+				instruction.body.run(enc)
+			case instruction.optional.presence.nullable:
+				enc.buf = append(enc.buf, "null"...)
+			}
+		case instruction.slot != nil:
+			start := len(enc.buf)
+			instruction.body.run(enc)
+			enc.spans[instruction.slot.index] = span{start: start, end: len(enc.buf)}
+		case instruction.reference != nil:
+			enc.holes = append(enc.holes, hole{at: len(enc.buf), reference: instruction.reference})
+		default:
+			enc.appendField(instruction.field)
+		}
+	}
+}
+
+// slot is a referenced value captured while a record is written.
+// A slot of a reference that refers to another reference takes the value of that one
+type slot struct {
+	index int
+	chain *reference
+}
+
+// reference is a referenceField whose value is copied from a slot
+type reference struct {
+	slot *slot
+}
+
+// referencePlan binds the references of a template to the slots of the values they copy
+type referencePlan struct {
+	slots      map[string]*slot      // slots by the keys of the paths of referenced values, see fieldPath.key
+	references map[string]*reference // references by the keys of their locations
+}
+
+// planReferences plans the references of a template. Only plain references to values that are written once
+// per record are compiled: interpolated references, uuid:v5 names taken from fields,
+// references into repeat items and references to values that contain other references are not
+func planReferences(fields []ParsedField, links []*dependencyLink) (*referencePlan, bool) {
+	plan := &referencePlan{
+		slots:      make(map[string]*slot),
+		references: make(map[string]*reference),
+	}
+	for _, link := range links {
+		if len(link.targets) == 0 {
+			continue
+		}
+
+		if _, ok := link.field.(*referenceField); !ok {
+			return nil, false
+		}
+
+		target := link.targets[0]
+		if !isCompilableTarget(fields, links, target) {
+			return nil, false
+		}
+
+		path := target.key()
+		targetSlot, ok := plan.slots[path]
+		if !ok {
+			targetSlot = &slot{index: len(plan.slots)}
+			plan.slots[path] = targetSlot
+		}
+		plan.references[link.location.key()] = &reference{slot: targetSlot}
+	}
+
+	for path, targetSlot := range plan.slots {
+		targetSlot.chain = plan.references[path]
+	}
+
+	return plan, true
+}
+
+// isCompilableTarget reports whether a referenced value is written once per record and contains no references
+func isCompilableTarget(fields []ParsedField, links []*dependencyLink, target fieldPath) bool {
+	field, ok := (&objectField{fields: fields}).field(target[0].key)
+	for i := 1; ok && i < len(target); i++ {
+		switch current := unwrapOptional(field).(type) {
+		case *objectField:
+			field, ok = current.field(target[i].key)
+		case *arrayField:
+			ok = target[i].isIndex() && target[i].index < len(current.items)
+			if ok {
+				field = current.items[target[i].index]
+			}
+		default:
+			ok = false
+		}
+	}
+
+	if !ok {
+		return false
+	}
+
+	for _, link := range links {
+		if len(link.targets) > 0 && len(link.location) > len(target) && target.overlaps(link.location) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// compiler builds a program, merging adjacent literals into one instruction
+type compiler struct {
+	instructions []instruction
+	path         fieldPath      // path of the value being compiled
+	plan         *referencePlan // references of the template
+	err          error
+}
+
+// compileTemplate compiles top-level fields of a template.
+// References are compiled as places that are filled with the captured values of their targets
+// when the whole record is written. Templates with references that can't be planned aren't compiled,
+// see planReferences, and nil is returned
+func compileTemplate(fields []ParsedField, links []*dependencyLink) (*program, error) {
+	plan, ok := planReferences(fields, links)
+	if !ok {
+		return nil, nil
+	}
+
+	compiler := &compiler{
+		plan: plan,
+	}
+	compiler.object(fields)
+	if compiler.err != nil {
+		return nil, compiler.err
+	}
+
+	return &program{
+		instructions: compiler.instructions,
+		slots:        len(plan.slots),
+	}, nil
+}
+
+// nestedCompiler returns a compiler of a program nested at the path of parent
+func nestedCompiler(parent *compiler) *compiler {
+	return &compiler{
+		path: parent.path,
+		plan: parent.plan,
+	}
+}
+
+// build returns a compiled nested program
+func (compiler *compiler) build() (*program, error) {
+	if compiler.err != nil {
+		return nil, compiler.err
+	}
+
+	return &program{
+		instructions: compiler.instructions,
+	}, nil
+}
+
+// at runs compile with the path of a nested value
+func (compiler *compiler) at(segment pathSegment, compile func()) {
+	compiler.path = compiler.path.child(segment)
+	compile()
+	compiler.path = compiler.path[:len(compiler.path)-1]
+}
+
+// object writes entries of an object. Entries of optional fields may be omitted,
+// so a comma that follows only optional entries is written at run time
+func (compiler *compiler) object(fields []ParsedField) {
+	compiler.literal("{")
+	var written bool // an entry that is always written goes before
+	for i, field := range fields {
+		optional, ok := field.(*optionalField)
+		switch {
+		case ok && !optional.presence.nullable:
+			nested := nestedCompiler(compiler)
+			nested.entry(i > 0, written, optional.field)
+			body, err := nested.build()
+			if err != nil {
+				compiler.fail(err)
+				return
+			}
+
+			compiler.instructions = append(compiler.instructions, instruction{
+				optional: optional,
+				body:     body,
+			})
+		default:
+			compiler.entry(i > 0, written, field)
+			written = true
+		}
+	}
+	compiler.literal("}")
+}
+
+// entry writes a key and a value of a field, separated reports whether there are entries before it
+func (compiler *compiler) entry(separated, written bool, field ParsedField) {
+	switch {
+	case written:
+		compiler.literal(",")
+	case separated:
+		compiler.instructions = append(compiler.instructions, instruction{separator: true})
+	}
+	compiler.static(field.Name())
+	compiler.literal(":")
+	compiler.at(pathSegment{key: field.Name(), index: keySegment}, func() {
+		compiler.value(field)
+	})
+}
+
+// value writes a value of a field, a referenced value is captured into its slot
+func (compiler *compiler) value(field ParsedField) {
+	targetSlot, ok := compiler.referenced()
+	if !ok {
+		compiler.write(field)
+		return
+	}
+
+	nested := nestedCompiler(compiler)
+	nested.write(field)
+	body, err := nested.build()
+	if err != nil {
+		compiler.fail(err)
+		return
+	}
+
+	compiler.instructions = append(compiler.instructions, instruction{
+		slot: targetSlot,
+		body: body,
+	})
+}
+
+// referenced returns a slot of the value being compiled if it is referenced
+func (compiler *compiler) referenced() (*slot, bool) {
+	if len(compiler.plan.slots) == 0 {
+		return nil, false
+	}
+
+	targetSlot, ok := compiler.plan.slots[compiler.path.key()]
+
+	return targetSlot, ok
+}
+
+func (compiler *compiler) write(field ParsedField) {
+	switch field := field.(type) {
+	case *staticField:
+		compiler.static(field.value)
+	case *objectField:
+		compiler.object(field.fields)
+	case *arrayField:
+		compiler.literal("[")
+		for i, item := range field.items {
+			if i > 0 {
+				compiler.literal(",")
+			}
+			compiler.at(pathSegment{index: i}, func() {
+				compiler.value(item)
+			})
+		}
+		compiler.literal("]")
+	case *repeatField:
+		nested := nestedCompiler(compiler)
+		nested.at(pathSegment{index: anyIndex}, func() {
+			nested.value(field.item)
+		})
+		body, err := nested.build()
+		if err != nil {
+			compiler.fail(err)
+			return
+		}
+
+		compiler.instructions = append(compiler.instructions, instruction{
+			repeat: field,
+			body:   body,
+		})
+	case *optionalField:
+		// a nullable field, its key is always written. The value lies at the same path, so it's written as is
+		nested := nestedCompiler(compiler)
+		nested.write(field.field)
+		body, err := nested.build()
+		if err != nil {
+			compiler.fail(err)
+			return
+		}
+
+		compiler.instructions = append(compiler.instructions, instruction{
+			optional: field,
+			body:     body,
+		})
+	case *referenceField:
+		fieldReference, ok := compiler.plan.references[compiler.path.key()]
+		if !ok {
+			compiler.fail(fmt.Errorf("reference %s is not linked", compiler.path))
+			return
+		}
+
+		compiler.instructions = append(compiler.instructions, instruction{reference: fieldReference})
+	default:
+		compiler.instructions = append(compiler.instructions, instruction{field: field})
+	}
+}
+
+// static writes a value known at compile time
+func (compiler *compiler) static(value interface{}) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		compiler.fail(err)
+		return
+	}
+
+	compiler.literal(string(b))
+}
+
+func (compiler *compiler) literal(literal string) {
+	if n := len(compiler.instructions); n > 0 && compiler.instructions[n-1].literal != nil {
+		compiler.instructions[n-1].literal = append(compiler.instructions[n-1].literal, literal...)
+		return
+	}
+
+	compiler.instructions = append(compiler.instructions, instruction{literal: []byte(literal)})
+}
+
+func (compiler *compiler) fail(err error) {
+	if compiler.err == nil {
+		compiler.err = err
+	}
+}
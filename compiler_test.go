@@ -0,0 +1,306 @@
+package goson
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_compileTemplate(t *testing.T) {
+	fields, err := newParserWithCustomKeywords(nil).Parse([]byte(`
+		{
+			"id": "_go:int(1, 9)",
+			"tags": ["a<b", 2, null],
+			"user": {"name": "_go:<3>", "active": true},
+			"items": ["_go:repeat(2)", {"sku": "_go:<2:digit>"}]
+		}
+	`))
+	require.NoError(t, err)
+
+	got, err := compileTemplate(fields, nil)
+	require.NoError(t, err)
+	require.Len(t, got.instructions, 7)
+
+	assert.Equal(t, `{"id":`, string(got.instructions[0].literal))
+	assert.IsType(t, &intField{}, got.instructions[1].field)
+	assert.Equal(t, `,"tags":["a\u003cb",2,null],"user":{"name":`, string(got.instructions[2].literal))
+	assert.IsType(t, &patternField{}, got.instructions[3].field)
+	assert.Equal(t, `,"active":true},"items":`, string(got.instructions[4].literal))
+	require.NotNil(t, got.instructions[5].repeat)
+	assert.Len(t, got.instructions[5].body.instructions, 3)
+	assert.Equal(t, `}`, string(got.instructions[6].literal))
+}
+
+func Test_compileTemplate_references(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		compiled bool
+	}{
+		{
+			name:     "plain reference",
+			body:     `{"password": "_go:<5>", "confirm": "_go:_password", "id": "_go:order-${int(1, 9)}"}`,
+			compiled: true,
+		},
+		{
+			name:     "chained and nested references",
+			body:     `{"c": "_go:_b", "b": "_go:_a.x[1]", "a": {"x": [1, "_go:<3>"]}, "d": ["_go:repeat(2)", "_go:_a"]}`,
+			compiled: true,
+		},
+		{
+			name:     "keys with dots",
+			body:     `{"a": {"b": "_go:<3:lower>"}, "a.b": "_go:<3:digit>", "r": "_go:_a.b"}`,
+			compiled: true,
+		},
+		{
+			name: "interpolated reference",
+			body: `{"password": "_go:<5>", "note": "_go:${_password}!"}`,
+		},
+		{
+			name: "reference into repeat items",
+			body: `{"items": ["_go:repeat(2)", {"sku": "_go:<3>"}], "first": "_go:_items[0].sku"}`,
+		},
+		{
+			name: "reference to a value with references",
+			body: `{"user": {"name": "_go:<3>", "login": "_go:_user.name"}, "copy": "_go:_user"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testParser := newParserWithCustomKeywords(nil)
+			fields, err := testParser.Parse([]byte(tt.body))
+			require.NoError(t, err)
+			links, err := testParser.Link(fields)
+			require.NoError(t, err)
+
+			got, err := compileTemplate(fields, links)
+			require.NoError(t, err)
+			require.Equal(t, tt.compiled, got != nil)
+			if !tt.compiled {
+				return
+			}
+
+			// the compiled references copy the same values as the resolved ones
+			compiled, err := New([]byte(tt.body), WithSeed(1))
+			require.NoError(t, err)
+			reference, err := New([]byte(tt.body), WithSeed(1))
+			require.NoError(t, err)
+			reference.(*processor).program = nil
+			for i := 0; i < 20; i++ {
+				assert.Equal(t, string(reference.Generate()), string(compiled.Generate()))
+			}
+		})
+	}
+}
+
+// Test_processor_Generate_compiledReferences checks that compiled references copy the same values
+// as the ones resolved in interface{} records
+func Test_processor_Generate_compiledReferences(t *testing.T) {
+	body := []byte(`
+		{
+			"confirm": "_go:_password",
+			"password": "_go:<5/2/1>",
+			"chain": "_go:_confirm",
+			"user": {"name": "_go:<3:upper>", "tags": ["_go:repeat(1, 3)", "_go:<2>"], "nick?0.5": "_go:<4>"},
+			"copy": "_go:_user",
+			"nick": "_go:_user.nick",
+			"tag": "_go:_user.tags",
+			"items": ["_go:repeat(0, 3)", {"owner": "_go:_user.name", "sku": "_go:<4:digit>"}],
+			"maybe??0.5": "_go:_password",
+			"skip?0.5": "_go:_user.name",
+			"fixed": ["a", {"b": "_go:int(1, 9)"}],
+			"b": "_go:_fixed[1].b"
+		}
+	`)
+
+	compiled, err := New(body, WithSeed(7))
+	require.NoError(t, err)
+	require.NotNil(t, compiled.(*processor).program)
+
+	reference, err := New(body, WithSeed(7))
+	require.NoError(t, err)
+	reference.(*processor).program = nil
+
+	for i := 0; i < 200; i++ {
+		assert.Equal(t, string(reference.Generate()), string(compiled.Generate()))
+	}
+
+	// a record appended after other data keeps its references
+	compiledRand, referenceRand := rand.New(rand.NewSource(1)), rand.New(rand.NewSource(1))
+	var got, want []byte
+	for i := 0; i < 50; i++ {
+		got, err = compiled.(*processor).appendRecord(append(got[:0], "prefix"...), compiledRand)
+		require.NoError(t, err)
+		want, err = reference.(*processor).appendRecord(append(want[:0], "prefix"...), referenceRand)
+		require.NoError(t, err)
+		assert.Equal(t, string(want), string(got))
+	}
+}
+
+// Test_processor_Generate_compiled checks that a compiled program gives the same records
+// as building and marshaling interface{} values with the same seed
+func Test_processor_Generate_compiled(t *testing.T) {
+	body := []byte(`
+		{
+			"id": "_go:uuid",
+			"name": "_go:<2-6:letter/1:special>",
+			"code": "_go:<2:upper>-<4:digit>",
+			"local": "_go:<3:cyrillic><1:emoji>  ",
+			"sku": "_go:regex(\"^[A-Z]{2}-\\\\d{4}[<>&]$\")",
+			"order": "_go:order-${int(1, 9)}-<2:hex>",
+			"count": "_go:int(-100, 100)",
+			"ratio": "_go:float(0, 1)",
+			"tiny": "_go:float(0, 0.000001)",
+			"huge": "_go:float(1e21, 1e22)",
+			"price": "_go:decimal(-100, 100, 2)",
+			"amount": "_go:decimal(0, 1, 4, string)",
+			"custom": "_go:custom",
+			"static": {"html": "<b>&</b>", "flag": false, "none": null, "num": 1.50},
+			"items": [
+				"_go:repeat(0, 4)",
+				{"sku": "_go:<4/4>", "tags": ["_go:repeat(1, 2)", "_go:<3>"]}
+			]
+		}
+	`)
+	custom := WithKeywords(map[string]NewFieldFunc{
+		"custom": func(key, value string) (ParsedField, error) {
+			return newStaticField(key, map[string]interface{}{"nested": []int{1, 2}}), nil
+		},
+	})
+
+	compiled, err := New(body, WithSeed(5), custom)
+	require.NoError(t, err)
+	require.NotNil(t, compiled.(*processor).program)
+
+	reference, err := New(body, WithSeed(5), custom)
+	require.NoError(t, err)
+	reference.(*processor).program = nil
+
+	for i := 0; i < 200; i++ {
+		got := compiled.Generate()
+		assert.True(t, json.Valid(got))
+		assert.Equal(t, string(reference.Generate()), string(got))
+	}
+}
+
+func Test_appendJSONString(t *testing.T) {
+	tests := []string{
+		"",
+		"plain",
+		`quote " and backslash \`,
+		"<html> & </html>",
+		"control \x00\x01\b\f\n\r\t\x1f\x7f",
+		"unicode: привет, 世界, 😀, é",
+		"separators    ",
+		"invalid \xff\xfe utf-8 \xe2\x82",
+	}
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			want, err := json.Marshal(tt)
+			require.NoError(t, err)
+			assert.Equal(t, string(want), string(appendJSONString(nil, []byte(tt))))
+		})
+	}
+}
+
+func Test_encoder_appendFloat(t *testing.T) {
+	tests := []float64{0, 1, -1.5, 0.1, 1e-6, 1e-7, 123456789.125, 1e20, 1e21, -3.5e-9, math.MaxFloat64, math.SmallestNonzeroFloat64}
+	for _, tt := range tests {
+		want, err := json.Marshal(tt)
+		require.NoError(t, err)
+
+		enc := &encoder{}
+		enc.appendFloat(tt)
+		require.NoError(t, enc.err)
+		assert.Equal(t, string(want), string(enc.buf))
+	}
+
+	enc := &encoder{}
+	enc.appendFloat(math.NaN())
+	assert.Error(t, enc.err)
+}
+
+const benchmarkTemplate = `
+	{
+		"id": "_go:uuid",
+		"email": "_go:<5:lower>@<4:lower>.com",
+		"password": "_go:<8/2/1>",
+		"age": "_go:int(18, 99)",
+		"balance": "_go:decimal(0, 10000, 2)",
+		"active": true,
+		"address": {"city": "_go:<8:letter>", "zip": "_go:<5:digit>"},
+		"tags": ["_go:repeat(3)", "_go:<6:lower>"]
+	}
+`
+
+func BenchmarkProcessor_Generate(b *testing.B) {
+	testProcessor, err := New([]byte(benchmarkTemplate), WithSeed(1))
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		testProcessor.Generate()
+	}
+}
+
+func BenchmarkProcessor_Generate_records(b *testing.B) {
+	testProcessor, err := New([]byte(benchmarkTemplate), WithSeed(1))
+	require.NoError(b, err)
+	testProcessor.(*processor).program = nil
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		testProcessor.Generate()
+	}
+}
+
+const benchmarkReferencesTemplate = `
+	{
+		"id": "_go:uuid",
+		"email": "_go:<5:lower>@<4:lower>.com",
+		"password": "_go:<8/2/1>",
+		"confirm_password": "_go:_password",
+		"address": {"city": "_go:<8:letter>", "zip": "_go:<5:digit>"},
+		"billing": "_go:_address"
+	}
+`
+
+func BenchmarkProcessor_Generate_references(b *testing.B) {
+	testProcessor, err := New([]byte(benchmarkReferencesTemplate), WithSeed(1))
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		testProcessor.Generate()
+	}
+}
+
+func BenchmarkProcessor_Generate_referencesRecords(b *testing.B) {
+	testProcessor, err := New([]byte(benchmarkReferencesTemplate), WithSeed(1))
+	require.NoError(b, err)
+	testProcessor.(*processor).program = nil
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		testProcessor.Generate()
+	}
+}
+
+func BenchmarkProcessor_GenerateTo(b *testing.B) {
+	testProcessor, err := New([]byte(benchmarkTemplate), WithSeed(1))
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	require.NoError(b, testProcessor.GenerateTo(context.Background(), io.Discard, b.N))
+}
@@ -0,0 +1,264 @@
+package goson
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// counter issues start, start+step, start+2*step... It is safe for a concurrent use
+type counter struct {
+	start, step int64
+	next        int64
+}
+
+func (counter *counter) issue() int64 {
+	return atomic.AddInt64(&counter.next, counter.step) - counter.step
+}
+
+func (counter *counter) reset() {
+	atomic.StoreInt64(&counter.next, counter.start)
+}
+
+func newCounter(start, step int64) *counter {
+	return &counter{
+		start: start,
+		step:  step,
+		next:  start,
+	}
+}
+
+// counterSet keeps counters of a processor by their names:
+// paths of the fields for sequences and given names for shared counters
+type counterSet struct {
+	mu       sync.Mutex
+	counters map[string]*counter
+}
+
+// register adds a counter or returns the registered one with the same name and settings
+func (counterSet *counterSet) register(name string, start, step int64) (*counter, error) {
+	counterSet.mu.Lock()
+	defer counterSet.mu.Unlock()
+
+	if registered, ok := counterSet.counters[name]; ok {
+		if registered.start != start || registered.step != step {
+			return nil, fmt.Errorf("counter %s is already defined with start %d and step %d",
+				name, registered.start, registered.step)
+		}
+
+		return registered, nil
+	}
+
+	counter := newCounter(start, step)
+	counterSet.counters[name] = counter
+
+	return counter, nil
+}
+
+// snapshot returns the next values of the counters
+func (counterSet *counterSet) snapshot() map[string]int64 {
+	counterSet.mu.Lock()
+	defer counterSet.mu.Unlock()
+
+	values := make(map[string]int64, len(counterSet.counters))
+	for name, counter := range counterSet.counters {
+		values[name] = atomic.LoadInt64(&counter.next)
+	}
+
+	return values
+}
+
+// restore sets the next values of the counters, all names must be known
+func (counterSet *counterSet) restore(values map[string]int64) error {
+	counterSet.mu.Lock()
+	defer counterSet.mu.Unlock()
+
+	var unknown []string
+	for name := range values {
+		if _, ok := counterSet.counters[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown counters: %v", unknown)
+	}
+
+	for name, value := range values {
+		atomic.StoreInt64(&counterSet.counters[name].next, value)
+	}
+
+	return nil
+}
+
+func (counterSet *counterSet) reset() {
+	counterSet.mu.Lock()
+	defer counterSet.mu.Unlock()
+
+	for _, counter := range counterSet.counters {
+		counter.reset()
+	}
+}
+
+func newCounterSet() *counterSet {
+	return &counterSet{
+		counters: make(map[string]*counter),
+	}
+}
+
+// countedField is a field whose counter is kept by a processor
+type countedField interface {
+	useCounters(counters *counterSet, path string) error
+}
+
+// sequenceField writes the values of a counter as integers,
+// or as strings when they are padded with zeros or prefixed
+type sequenceField struct {
+	name        string
+	shared      string // a name of a shared counter, empty for a sequence of the field
+	start, step int64
+	padding     int
+	prefix      string
+	counter     *counter
+}
+
+func (sequenceField *sequenceField) Name() string {
+	return sequenceField.name
+}
+
+func (sequenceField *sequenceField) Value() interface{} {
+	value := sequenceField.issue()
+	if sequenceField.padding == 0 && sequenceField.prefix == "" {
+		return value
+	}
+
+	return string(sequenceField.appendFormatted(nil, value))
+}
+
+func (sequenceField *sequenceField) appendJSON(enc *encoder) {
+	value := sequenceField.issue()
+	if sequenceField.padding == 0 && sequenceField.prefix == "" {
+		enc.buf = strconv.AppendInt(enc.buf, value, 10)
+		return
+	}
+
+	enc.scratch = sequenceField.appendFormatted(enc.scratch[:0], value)
+	enc.appendScratch()
+}
+
+func (sequenceField *sequenceField) issue() int64 {
+	return sequenceField.counter.issue()
+}
+
+// appendFormatted appends a prefix and a value padded with zeros to the padding width
+func (sequenceField *sequenceField) appendFormatted(dst []byte, value int64) []byte {
+	dst = append(dst, sequenceField.prefix...)
+	if value < 0 {
+		dst = append(dst, '-')
+		value = -value
+	}
+
+	digits := 1
+	for rest := value; rest >= 10; rest /= 10 {
+		digits++
+	}
+
+	for i := digits; i < sequenceField.padding; i++ {
+		dst = append(dst, '0')
+	}
+
+	return strconv.AppendInt(dst, value, 10)
+}
+
+func (sequenceField *sequenceField) useCounters(counters *counterSet, path string) error {
+	name := sequenceField.shared
+	if name == "" {
+		name = path
+	}
+
+	counter, err := counters.register(name, sequenceField.start, sequenceField.step)
+	if err != nil {
+		return err
+	}
+	sequenceField.counter = counter
+
+	return nil
+}
+
+const sequenceMaxPadding = 32
+
+// newSequenceField handles "seq" keyword: "_go:seq([start[, step[, padding[, prefix]]]])".
+// Every field, and every ${seq} expression of a string, counts on its own from start, 1 by default, with step, 1 by default.
+// Padding and prefix make strings: "_go:seq(1, 1, 6, INV-)" gives INV-000001, INV-000002...
+// Values are issued in the order records are generated: concurrent generation gets them in any order,
+// GenerateParallel with WithOrdered gets them in the order of the records
+func newSequenceField(name string, call *KeywordCall) (ParsedField, error) {
+	if err := call.Arity(0, 4); err != nil {
+		return nil, err
+	}
+
+	return parseSequence(name, "", call, 0)
+}
+
+// newCounterField handles "counter" keyword: "_go:counter(name[, start[, step[, padding[, prefix]]]])".
+// Fields that use a counter of the same name share it, so their values never repeat within a processor
+func newCounterField(name string, call *KeywordCall) (ParsedField, error) {
+	if err := call.Arity(1, 5); err != nil {
+		return nil, err
+	}
+
+	shared, err := call.String(0)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSequence(name, shared, call, 1)
+}
+
+// parseSequence parses start, step, padding and prefix that go after the first skip arguments
+func parseSequence(name, shared string, call *KeywordCall, skip int) (ParsedField, error) {
+	// start, step and padding
+	settings := []int64{1, 1, 0}
+	for i := range settings {
+		if skip+i >= len(call.Args) {
+			break
+		}
+
+		value, err := call.Int(skip + i)
+		if err != nil {
+			return nil, err
+		}
+		settings[i] = value
+	}
+
+	start, step, padding := settings[0], settings[1], settings[2]
+	if step == 0 {
+		return nil, fmt.Errorf("keyword %s: step must not be zero", call.Keyword)
+	}
+
+	if padding < 0 || padding > sequenceMaxPadding {
+		return nil, fmt.Errorf("keyword %s: padding must be within [0, %d]", call.Keyword, sequenceMaxPadding)
+	}
+
+	var prefix string
+	if i := skip + len(settings); i < len(call.Args) {
+		var err error
+		if prefix, err = call.String(i); err != nil {
+			return nil, err
+		}
+	}
+
+	// a field counts on its own until a processor binds it to its counters
+	return &sequenceField{
+		name:    name,
+		shared:  shared,
+		start:   start,
+		step:    step,
+		padding: int(padding),
+		prefix:  prefix,
+		counter: newCounter(start, step),
+	}, nil
+}
@@ -0,0 +1,220 @@
+package goson
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newSequenceField(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    *sequenceField
+		wantErr bool
+	}{
+		{
+			name:  "default",
+			input: "seq",
+			want:  &sequenceField{name: "id", start: 1, step: 1},
+		},
+		{
+			name:  "start and step",
+			input: "seq(100, -10)",
+			want:  &sequenceField{name: "id", start: 100, step: -10},
+		},
+		{
+			name:  "padding and prefix",
+			input: "seq(1, 1, 6, INV-)",
+			want:  &sequenceField{name: "id", start: 1, step: 1, padding: 6, prefix: "INV-"},
+		},
+		{
+			name:  "shared counter",
+			input: `counter(orders, 1000, 1, 0, "ORD-")`,
+			want:  &sequenceField{name: "id", shared: "orders", start: 1000, step: 1, prefix: "ORD-"},
+		},
+		{
+			name:    "zero step",
+			input:   "seq(1, 0)",
+			wantErr: true,
+		},
+		{
+			name:    "negative padding",
+			input:   "seq(1, 1, -1)",
+			wantErr: true,
+		},
+		{
+			name:    "not an integer",
+			input:   "seq(1.5)",
+			wantErr: true,
+		},
+		{
+			name:    "counter without a name",
+			input:   "counter",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call, err := parseKeywordCall(tt.input)
+			require.NoError(t, err)
+
+			fn := newSequenceField
+			if call.Keyword == "counter" {
+				fn = newCounterField
+			}
+
+			got, err := fn("id", call)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			tt.want.counter = newCounter(tt.want.start, tt.want.step)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_sequenceField_Value(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []interface{}
+	}{
+		{input: "seq", want: []interface{}{int64(1), int64(2), int64(3)}},
+		{input: "seq(10, 5)", want: []interface{}{int64(10), int64(15), int64(20)}},
+		{input: "seq(1, -1, 2)", want: []interface{}{"01", "00", "-01"}},
+		{input: "seq(998, 1, 3, INV-)", want: []interface{}{"INV-998", "INV-999", "INV-1000"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			call, err := parseKeywordCall(tt.input)
+			require.NoError(t, err)
+
+			field, err := newSequenceField("id", call)
+			require.NoError(t, err)
+
+			for _, want := range tt.want {
+				assert.Equal(t, want, field.Value())
+			}
+		})
+	}
+}
+
+func Test_processor_Generate_sequences(t *testing.T) {
+	body := []byte(`
+		{
+			"id": "_go:seq",
+			"invoice": "_go:seq(1, 1, 6, INV-)",
+			"orders": ["_go:repeat(2)", {"id": "_go:counter(orders, 100)", "line": "_go:seq"}],
+			"refund": "_go:counter(orders, 100)",
+			"label": "_go:#${seq(7)}"
+		}
+	`)
+
+	testProcessor, err := New(body)
+	require.NoError(t, err)
+
+	expect := []string{
+		`{"id":1,"invoice":"INV-000001","orders":[{"id":100,"line":1},{"id":101,"line":2}],"refund":102,"label":"#7"}`,
+		`{"id":2,"invoice":"INV-000002","orders":[{"id":103,"line":3},{"id":104,"line":4}],"refund":105,"label":"#8"}`,
+	}
+	for _, want := range expect {
+		assert.Equal(t, want, string(testProcessor.Generate()))
+	}
+
+	snapshot := testProcessor.Counters()
+	assert.Equal(t, map[string]int64{
+		"id":             3,
+		"invoice":        3,
+		"orders":         106,
+		"orders[1].line": 5,
+		"label#1":        9,
+	}, snapshot)
+
+	testProcessor.Generate()
+	require.NoError(t, testProcessor.SetCounters(snapshot))
+	assert.Equal(t, snapshot, testProcessor.Counters())
+
+	testProcessor.ResetCounters()
+	assert.Equal(t, expect[0], string(testProcessor.Generate()))
+
+	assert.Error(t, testProcessor.SetCounters(map[string]int64{"id": 1, "missing": 1}))
+	assert.Equal(t, int64(2), testProcessor.Counters()["id"])
+}
+
+func Test_processor_Generate_sequencesInterpolated(t *testing.T) {
+	testProcessor, err := New([]byte(`{"pair": "_go:${seq}-${seq}", "range": "_go:${seq(1)}..${seq(100, 10)}"}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, `{"pair":"1-1","range":"1..100"}`, string(testProcessor.Generate()))
+	assert.Equal(t, `{"pair":"2-2","range":"2..110"}`, string(testProcessor.Generate()))
+	assert.Equal(t, map[string]int64{"pair#1": 3, "pair#2": 3, "range#1": 3, "range#2": 120}, testProcessor.Counters())
+}
+
+func Test_processor_Generate_sequencesWithReferences(t *testing.T) {
+	for _, compiled := range []bool{true, false} {
+		testProcessor, err := New([]byte(`{"id": "_go:seq", "copy": "_go:_id"}`))
+		require.NoError(t, err)
+		require.NotNil(t, testProcessor.(*processor).program)
+		if !compiled {
+			testProcessor.(*processor).program = nil
+		}
+
+		assert.Equal(t, `{"id":1,"copy":1}`, string(testProcessor.Generate()))
+		assert.Equal(t, `{"id":2,"copy":2}`, string(testProcessor.Generate()))
+	}
+}
+
+func Test_processor_Generate_sequencesConcurrent(t *testing.T) {
+	testProcessor, err := New([]byte(`{"id": "_go:seq", "shared": "_go:counter(ids)", "other": "_go:counter(ids)"}`))
+	require.NoError(t, err)
+
+	const workers, records = 8, 200
+	ids := make(chan int64, workers*records)
+	shared := make(chan int64, 2*workers*records)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < records; j++ {
+				var record map[string]int64
+				if assert.NoError(t, json.Unmarshal(testProcessor.Generate(), &record)) {
+					ids <- record["id"]
+					shared <- record["shared"]
+					shared <- record["other"]
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+	close(shared)
+
+	for values, n := range map[chan int64]int{ids: workers * records, shared: 2 * workers * records} {
+		var got []int
+		for value := range values {
+			got = append(got, int(value))
+		}
+		sort.Ints(got)
+
+		require.Len(t, got, n)
+		for i, value := range got {
+			assert.Equal(t, i+1, value)
+		}
+	}
+}
+
+func TestNew_sequenceConflict(t *testing.T) {
+	_, err := New([]byte(`{"a": "_go:counter(ids, 1)", "b": "_go:counter(ids, 10)"}`))
+	var keywordErr *KeywordError
+	require.ErrorAs(t, err, &keywordErr)
+	assert.Equal(t, "b", keywordErr.Path)
+}
@@ -0,0 +1,219 @@
+package goson
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"unicode/utf8"
+)
+
+// encoder is a reusable state of a compiled generation.
+// It appends JSON into buf, string values are generated into scratch first and escaped into buf
+type encoder struct {
+	rnd     *rand.Rand
+	buf     []byte
+	scratch []byte
+	spans   []span // captured referenced values of a record by their slots
+	holes   []hole // places of reference values of a record in the order they are written
+	err     error
+}
+
+// span is a part of buf, start is -1 when the part isn't written, e.g. it's a value of a missing optional field
+type span struct {
+	start, end int
+}
+
+// hole is a place in buf where a value of a reference goes
+type hole struct {
+	at        int
+	reference *reference
+}
+
+var jsonNull = []byte("null")
+
+// begin prepares the encoder for a record of a program
+func (enc *encoder) begin(program *program) {
+	enc.spans, enc.holes = enc.spans[:0], enc.holes[:0]
+	for i := 0; i < program.slots; i++ {
+		enc.spans = append(enc.spans, span{start: -1, end: -1})
+	}
+}
+
+// fillHoles writes the values of references into their places of a record that starts at start in buf
+func (enc *encoder) fillHoles(start int) {
+	if len(enc.holes) == 0 {
+		return
+	}
+
+	// the record is moved into scratch, spans and holes are offsets of buf, so they are shifted by start
+	enc.scratch = append(enc.scratch[:0], enc.buf[start:]...)
+	enc.buf = enc.buf[:start]
+
+	written := 0
+	for _, hole := range enc.holes {
+		at := hole.at - start
+		enc.buf = append(enc.buf, enc.scratch[written:at]...)
+		enc.buf = append(enc.buf, enc.referenced(hole.reference, start)...)
+		written = at
+	}
+
+	enc.buf = append(enc.buf, enc.scratch[written:]...)
+}
+
+// referenced returns the JSON of a reference value from the record moved into scratch
+func (enc *encoder) referenced(reference *reference, start int) []byte {
+	span := enc.spans[reference.slot.index]
+	switch {
+	case span.start < 0:
+		return jsonNull
+	case reference.slot.chain != nil:
+		return enc.referenced(reference.slot.chain, start)
+	}
+
+	return enc.scratch[span.start-start : span.end-start]
+}
+
+// jsonAppender is a field that appends its JSON value straight into a buffer,
+// it draws the same randomness as its valueWith, so both ways give the same records
+type jsonAppender interface {
+	appendJSON(enc *encoder)
+}
+
+func (enc *encoder) appendField(field ParsedField) {
+	switch field := field.(type) {
+	case jsonAppender:
+		field.appendJSON(enc)
+	case draftField:
+		enc.appendValue(field.complete(field.valueWith(enc.rnd), nil))
+	default:
+		enc.appendValue(fieldValue(field, enc.rnd))
+	}
+}
+
+// appendValue appends a generated value, types without a fast path are encoded with json.Marshal
+func (enc *encoder) appendValue(value interface{}) {
+	switch value := value.(type) {
+	case nil:
+		enc.buf = append(enc.buf, "null"...)
+	case string:
+		enc.scratch = append(enc.scratch[:0], value...)
+		enc.appendScratch()
+	case json.Number:
+		enc.buf = append(enc.buf, value...)
+	case bool:
+		enc.buf = strconv.AppendBool(enc.buf, value)
+	case int:
+		enc.buf = strconv.AppendInt(enc.buf, int64(value), 10)
+	case int64:
+		enc.buf = strconv.AppendInt(enc.buf, value, 10)
+	case float64:
+		enc.appendFloat(value)
+	default:
+		b, err := json.Marshal(value)
+		if err != nil {
+			enc.fail(err)
+			return
+		}
+		enc.buf = append(enc.buf, b...)
+	}
+}
+
+// appendScratch escapes a string value generated into scratch
+func (enc *encoder) appendScratch() {
+	enc.buf = appendJSONString(enc.buf, enc.scratch)
+}
+
+// appendFloat formats a float the way encoding/json does
+func (enc *encoder) appendFloat(value float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		enc.fail(fmt.Errorf("unsupported float value: %v", value))
+		return
+	}
+
+	format := byte('f')
+	if abs := math.Abs(value); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+
+	enc.buf = strconv.AppendFloat(enc.buf, value, format, -1, 64)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(enc.buf)
+		if n >= 4 && enc.buf[n-4] == 'e' && enc.buf[n-3] == '-' && enc.buf[n-2] == '0' {
+			enc.buf[n-2] = enc.buf[n-1]
+			enc.buf = enc.buf[:n-1]
+		}
+	}
+}
+
+// fail keeps the first error of a record
+func (enc *encoder) fail(err error) {
+	if enc.err == nil {
+		enc.err = err
+	}
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends a quoted and escaped string, the same way encoding/json does:
+// HTML characters are escaped, as well as U+2028 and U+2029, and invalid UTF-8 is replaced with U+FFFD
+func appendJSONString(dst []byte, s []byte) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if jsonSafe(b) {
+				i++
+				continue
+			}
+
+			dst = append(dst, s[start:i]...)
+			dst = appendJSONEscape(dst, b)
+			i++
+			start = i
+			continue
+		}
+
+		char, size := utf8.DecodeRune(s[i:])
+		if char == utf8.RuneError && size == 1 || char == '\u2028' || char == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			if char == utf8.RuneError {
+				dst = utf8.AppendRune(dst, utf8.RuneError)
+			} else {
+				dst = append(dst, `\u202`...)
+				dst = append(dst, hexDigits[char&0xf])
+			}
+			start = i + size
+		}
+		i += size
+	}
+
+	dst = append(dst, s[start:]...)
+
+	return append(dst, '"')
+}
+
+func jsonSafe(b byte) bool {
+	return b >= ' ' && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&'
+}
+
+func appendJSONEscape(dst []byte, b byte) []byte {
+	switch b {
+	case '"', '\\':
+		return append(dst, '\\', b)
+	case '\b':
+		return append(dst, '\\', 'b')
+	case '\f':
+		return append(dst, '\\', 'f')
+	case '\n':
+		return append(dst, '\\', 'n')
+	case '\r':
+		return append(dst, '\\', 'r')
+	case '\t':
+		return append(dst, '\\', 't')
+	default:
+		return append(dst, '\\', 'u', '0', '0', hexDigits[b>>4], hexDigits[b&0xf])
+	}
+}
@@ -1,19 +1,175 @@
 package goson
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
-type invalidPatternError struct {
-	pattern string
-	err     error
+// Every error of a template carries a location of the offending field:
+// its JSON path, e.g. "user.emails[0]", and a byte offset of its value in the template.
+// Offset is -1 when it is unknown, e.g. for an error returned outside of a template parsing.
+
+// PatternError is returned for a pattern that can't be parsed, e.g. "_go:<5/a>"
+type PatternError struct {
+	Path    string
+	Offset  int64
+	Pattern string
+	Err     error
+}
+
+func (patternError *PatternError) Error() string {
+	return errorLocation(patternError.Path, patternError.Offset) +
+		fmt.Sprintf("invalid pattern: %s, reason: %v", patternError.Pattern, patternError.Err)
 }
 
-func (invalidPatternError *invalidPatternError) Error() string {
-	return fmt.Sprintf("invalid pattern: %s, reason: %v", invalidPatternError.pattern, invalidPatternError.err)
+func (patternError *PatternError) Unwrap() error {
+	return patternError.Err
 }
 
-func throwInvalidPatternError(pattern string, err error) *invalidPatternError {
-	return &invalidPatternError{
-		pattern: pattern,
-		err:     err,
+func throwInvalidPatternError(pattern string, err error) *PatternError {
+	return &PatternError{
+		Offset:  -1,
+		Pattern: pattern,
+		Err:     err,
 	}
 }
+
+// UnknownKeywordError is returned for a directive that is neither a pattern, a reference nor a known keyword
+type UnknownKeywordError struct {
+	Path    string
+	Offset  int64
+	Keyword string
+}
+
+func (unknownKeywordError *UnknownKeywordError) Error() string {
+	return errorLocation(unknownKeywordError.Path, unknownKeywordError.Offset) +
+		fmt.Sprintf("unknown keyword: %s", unknownKeywordError.Keyword)
+}
+
+// KeywordError is returned when a keyword rejects its arguments
+type KeywordError struct {
+	Path    string
+	Offset  int64
+	Keyword string
+	Err     error
+}
+
+func (keywordError *KeywordError) Error() string {
+	return errorLocation(keywordError.Path, keywordError.Offset) + keywordError.Err.Error()
+}
+
+func (keywordError *KeywordError) Unwrap() error {
+	return keywordError.Err
+}
+
+// ReferenceError is returned for a reference to a missing field or for a reference cycle
+type ReferenceError struct {
+	Path      string
+	Offset    int64
+	Reference string
+	Err       error
+}
+
+func (referenceError *ReferenceError) Error() string {
+	return errorLocation(referenceError.Path, referenceError.Offset) + referenceError.Err.Error()
+}
+
+func (referenceError *ReferenceError) Unwrap() error {
+	return referenceError.Err
+}
+
+// SyntaxError is returned for a malformed JSON, keyword call or reference path
+type SyntaxError struct {
+	Path   string
+	Offset int64
+	Err    error
+}
+
+func (syntaxError *SyntaxError) Error() string {
+	return errorLocation(syntaxError.Path, syntaxError.Offset) + syntaxError.Err.Error()
+}
+
+func (syntaxError *SyntaxError) Unwrap() error {
+	return syntaxError.Err
+}
+
+// UniqueError is returned when a unique field can't generate a value that it hasn't generated yet:
+// every possible value is taken, or every retry gives a taken one
+type UniqueError struct {
+	Path      string
+	Generated int     // a number of distinct values generated by the field
+	Capacity  float64 // a number of possible values, +Inf when it is unknown
+	Retries   int
+}
+
+func (uniqueError *UniqueError) Error() string {
+	if uniqueError.Exhausted() {
+		return errorLocation(uniqueError.Path, -1) +
+			fmt.Sprintf("unique values are exhausted, all %d possible values are generated", uniqueError.Generated)
+	}
+
+	return errorLocation(uniqueError.Path, -1) + fmt.Sprintf(
+		"no unique value after %d retries, %d of %g possible values are generated",
+		uniqueError.Retries, uniqueError.Generated, uniqueError.Capacity,
+	)
+}
+
+// Exhausted reports whether every possible value of the field is generated
+func (uniqueError *UniqueError) Exhausted() bool {
+	return float64(uniqueError.Generated) >= uniqueError.Capacity
+}
+
+// MultiError reports every problem of a template at once, see WithAllErrors
+type MultiError struct {
+	Errors []error
+}
+
+func (multiError *MultiError) Error() string {
+	messages := make([]string, len(multiError.Errors))
+	for i, err := range multiError.Errors {
+		messages[i] = err.Error()
+	}
+
+	return fmt.Sprintf("%d errors: %s", len(messages), strings.Join(messages, "; "))
+}
+
+// Unwrap returns every error of the list, errors.Is and errors.As walk it since Go 1.20
+func (multiError *MultiError) Unwrap() []error {
+	return multiError.Errors
+}
+
+// Is lets errors.Is look into every error of the list on Go versions before 1.20
+func (multiError *MultiError) Is(target error) bool {
+	for _, err := range multiError.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As lets errors.As look into every error of the list on Go versions before 1.20
+func (multiError *MultiError) As(target interface{}) bool {
+	for _, err := range multiError.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func errorLocation(path string, offset int64) string {
+	switch {
+	case path != "" && offset >= 0:
+		return fmt.Sprintf("field %s at offset %d: ", path, offset)
+	case path != "":
+		return fmt.Sprintf("field %s: ", path)
+	case offset >= 0:
+		return fmt.Sprintf("offset %d: ", offset)
+	}
+
+	return ""
+}
@@ -0,0 +1,186 @@
+package goson
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_errors(t *testing.T) {
+	t.Run("pattern error", func(t *testing.T) {
+		body := []byte(`{"user": {"emails": ["ok", "_go:<5/a>"]}}`)
+		_, err := New(body)
+
+		var target *PatternError
+		require.ErrorAs(t, err, &target)
+		assert.Equal(t, "user.emails[1]", target.Path)
+		assert.Equal(t, int64(27), target.Offset)
+		assert.Equal(t, "<5/a>", target.Pattern)
+		assert.Equal(t, `"_go:<5/a>"`, string(body[target.Offset:target.Offset+11]))
+	})
+
+	t.Run("unknown keyword", func(t *testing.T) {
+		body := []byte(`{"status": "_go:state(1)"}`)
+		_, err := New(body)
+
+		var target *UnknownKeywordError
+		require.ErrorAs(t, err, &target)
+		assert.Equal(t, "status", target.Path)
+		assert.Equal(t, int64(11), target.Offset)
+		assert.Equal(t, "state", target.Keyword)
+		assert.EqualError(t, err, "field status at offset 11: unknown keyword: state")
+	})
+
+	t.Run("keyword error", func(t *testing.T) {
+		body := []byte(`{"n": "_go:int(5, 1)"}`)
+		_, err := New(body)
+
+		var target *KeywordError
+		require.ErrorAs(t, err, &target)
+		assert.Equal(t, "n", target.Path)
+		assert.Equal(t, "int", target.Keyword)
+	})
+
+	t.Run("unknown reference", func(t *testing.T) {
+		body := []byte(`{"confirm": "_go:_password"}`)
+		_, err := New(body)
+
+		var target *ReferenceError
+		require.ErrorAs(t, err, &target)
+		assert.Equal(t, "confirm", target.Path)
+		assert.Equal(t, "password", target.Reference)
+	})
+
+	t.Run("unknown nested reference", func(t *testing.T) {
+		body := []byte(`{"user": {"city": "x"}, "city": "_go:_user.zip"}`)
+		_, err := New(body)
+
+		var target *ReferenceError
+		require.ErrorAs(t, err, &target)
+		assert.Equal(t, "city", target.Path)
+		assert.Equal(t, int64(32), target.Offset)
+		assert.Equal(t, "user.zip", target.Reference)
+	})
+
+	t.Run("reference cycle", func(t *testing.T) {
+		body := []byte(`{"a": "_go:_b", "b": "_go:_a"}`)
+		_, err := New(body)
+
+		var target *ReferenceError
+		require.ErrorAs(t, err, &target)
+		assert.EqualError(t, err, "field a at offset 6: reference cycle: a -> b -> a")
+	})
+
+	t.Run("json syntax error", func(t *testing.T) {
+		body := []byte(`{"user": {"email": "x",}}`)
+		_, err := New(body)
+
+		var target *SyntaxError
+		require.ErrorAs(t, err, &target)
+		assert.Equal(t, "user", target.Path)
+		assert.Equal(t, int64(23), target.Offset)
+	})
+
+	t.Run("keyword syntax error", func(t *testing.T) {
+		body := []byte(`{"n": "_go:int(1, 2"}`)
+		_, err := New(body)
+
+		var target *SyntaxError
+		require.ErrorAs(t, err, &target)
+		assert.Equal(t, "n", target.Path)
+	})
+}
+
+func TestWithAllErrors(t *testing.T) {
+	body := []byte(`
+		{
+			"email": "_go:<5/a>",
+			"status": "_go:state",
+			"ok": "_go:<5>",
+			"user": {
+				"n": "_go:int(5, 1)",
+				"confirm": "_go:_password"
+			}
+		}
+	`)
+
+	_, err := New(body)
+	require.Error(t, err)
+	var multi *MultiError
+	assert.False(t, errors.As(err, &multi))
+
+	_, err = New(body, WithAllErrors())
+	require.ErrorAs(t, err, &multi)
+	require.Len(t, multi.Errors, 4)
+
+	paths := make([]string, 0, len(multi.Errors))
+	for _, err := range multi.Errors {
+		var patternErr *PatternError
+		var keywordErr *UnknownKeywordError
+		var argsErr *KeywordError
+		var referenceErr *ReferenceError
+		switch {
+		case errors.As(err, &patternErr):
+			paths = append(paths, patternErr.Path)
+		case errors.As(err, &keywordErr):
+			paths = append(paths, keywordErr.Path)
+		case errors.As(err, &argsErr):
+			paths = append(paths, argsErr.Path)
+		case errors.As(err, &referenceErr):
+			paths = append(paths, referenceErr.Path)
+		}
+	}
+	assert.Equal(t, []string{"email", "status", "user.n", "user.confirm"}, paths)
+
+	var referenceErr *ReferenceError
+	assert.ErrorAs(t, err, &referenceErr)
+}
+
+func TestWithAllErrors_references(t *testing.T) {
+	body := []byte(`{"a": "_go:_b.x", "b": {"y": 1}, "c": "_go:_b[0]"}`)
+	_, err := New(body, WithAllErrors())
+
+	var multi *MultiError
+	require.ErrorAs(t, err, &multi)
+	require.Len(t, multi.Errors, 2)
+	assert.EqualError(t, multi.Errors[0], "field a at offset 6: unknown reference: b.x")
+	assert.EqualError(t, multi.Errors[1], "field c at offset 38: unknown reference: b[0]")
+}
+
+func TestWithAllErrors_parseAndReferences(t *testing.T) {
+	body := []byte(`{"a": "_go:<5/x>", "b": "_go:_c.zz", "c": {"y": 1}, "d": ["_go:repeat(x)", 1], "e": "_go:_d[0]"}`)
+	_, err := New(body, WithAllErrors())
+
+	var multi *MultiError
+	require.ErrorAs(t, err, &multi)
+	require.Len(t, multi.Errors, 3)
+
+	var patternErr *PatternError
+	require.ErrorAs(t, multi.Errors[0], &patternErr)
+	assert.Equal(t, "a", patternErr.Path)
+	var keywordErr *KeywordError
+	require.ErrorAs(t, multi.Errors[1], &keywordErr)
+	assert.Equal(t, "d", keywordErr.Path)
+	// e refers into d that failed to parse, so it isn't reported on its own
+	assert.EqualError(t, multi.Errors[2], "field b at offset 24: unknown reference: c.zz")
+}
+
+func TestMultiError_IsAs(t *testing.T) {
+	referenceErr := &ReferenceError{Path: "a", Offset: -1, Reference: "b", Err: errors.New("unknown reference: b")}
+	multi := &MultiError{
+		Errors: []error{&PatternError{Path: "c", Offset: -1, Err: io.ErrUnexpectedEOF}, referenceErr},
+	}
+
+	// the methods are called directly, errors.Is and errors.As don't need them since Go 1.20
+	var target *ReferenceError
+	require.True(t, multi.As(&target))
+	assert.Same(t, referenceErr, target)
+	assert.True(t, multi.Is(io.ErrUnexpectedEOF))
+	assert.False(t, multi.Is(io.EOF))
+
+	var uniqueErr *UniqueError
+	assert.False(t, multi.As(&uniqueErr))
+}
@@ -1,7 +1,13 @@
 package goson
 
 import (
-	"time"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
 )
 
 // ParsedField is a post-processed input field
@@ -13,6 +19,24 @@ type ParsedField interface {
 // NewFieldFunc signature for a custom keyword fields
 type NewFieldFunc func(key, value string) (ParsedField, error)
 
+// dependentField is a field whose value is computed from the values of other fields of a record.
+// Dependencies are reference paths like "password" or "user.address.city",
+// their values are passed to valueFrom in the same order
+type dependentField interface {
+	ParsedField
+	dependencies() []string
+	valueFrom(values []interface{}) interface{}
+}
+
+// draftField is a dependentField that also draws random values.
+// The draft generated with valueWith is stored in a record first,
+// and it is completed with the values of the dependencies when they are resolved
+type draftField interface {
+	dependentField
+	valueWith(rnd *rand.Rand) interface{}
+	complete(draft interface{}, values []interface{}) interface{}
+}
+
 type staticField struct {
 	name  string
 	value interface{}
@@ -33,6 +57,210 @@ func newStaticField(name string, value interface{}) ParsedField {
 	}
 }
 
+// objectField is a nested object. Its fields keep the order of the template
+type objectField struct {
+	name   string
+	fields []ParsedField
+}
+
+func (objectField *objectField) Name() string {
+	return objectField.name
+}
+
+func (objectField *objectField) Value() interface{} {
+	return objectField.valueWith(fallbackRand)
+}
+
+func (objectField *objectField) valueWith(rnd *rand.Rand) interface{} {
+	value := newOrderedObject(len(objectField.fields))
+	for _, field := range objectField.fields {
+		if entry, ok := entryValue(field, rnd); ok {
+			value.set(field.Name(), entry)
+		}
+	}
+
+	return value
+}
+
+// field returns a nested field by its name
+func (objectField *objectField) field(name string) (ParsedField, bool) {
+	for _, field := range objectField.fields {
+		if field.Name() == name {
+			return field, true
+		}
+	}
+
+	return nil, false
+}
+
+func newObjectField(name string, fields []ParsedField) ParsedField {
+	return &objectField{
+		name:   name,
+		fields: fields,
+	}
+}
+
+// orderedObject is a generated JSON object that is marshaled in the order of its keys
+type orderedObject struct {
+	keys   []string
+	values []interface{}
+}
+
+// get returns a value by its key
+func (orderedObject *orderedObject) get(key string) (interface{}, bool) {
+	for i := range orderedObject.keys {
+		if orderedObject.keys[i] == key {
+			return orderedObject.values[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// set replaces a value of an existing key or appends a new one
+func (orderedObject *orderedObject) set(key string, value interface{}) {
+	for i := range orderedObject.keys {
+		if orderedObject.keys[i] == key {
+			orderedObject.values[i] = value
+			return
+		}
+	}
+
+	orderedObject.keys = append(orderedObject.keys, key)
+	orderedObject.values = append(orderedObject.values, value)
+}
+
+func (orderedObject *orderedObject) MarshalJSON() ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, 64))
+	buf.WriteByte('{')
+	for i, key := range orderedObject.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		rawKey, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(rawKey)
+		buf.WriteByte(':')
+
+		rawValue, err := json.Marshal(orderedObject.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(rawValue)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+func newOrderedObject(size int) *orderedObject {
+	return &orderedObject{
+		keys:   make([]string, 0, size),
+		values: make([]interface{}, 0, size),
+	}
+}
+
+type arrayField struct {
+	name  string
+	items []ParsedField
+}
+
+func (arrayField *arrayField) Name() string {
+	return arrayField.name
+}
+
+func (arrayField *arrayField) Value() interface{} {
+	return arrayField.valueWith(fallbackRand)
+}
+
+func (arrayField *arrayField) valueWith(rnd *rand.Rand) interface{} {
+	value := make([]interface{}, len(arrayField.items))
+	for i, item := range arrayField.items {
+		value[i] = fieldValue(item, rnd)
+	}
+
+	return value
+}
+
+func newArrayField(name string, items []ParsedField) ParsedField {
+	return &arrayField{
+		name:  name,
+		items: items,
+	}
+}
+
+// repeatField generates an array of a random length within [min, max],
+// where every item is generated independently from the same template
+type repeatField struct {
+	name     string
+	min, max int
+	item     ParsedField
+}
+
+func (repeatField *repeatField) Name() string {
+	return repeatField.name
+}
+
+func (repeatField *repeatField) Value() interface{} {
+	return repeatField.valueWith(fallbackRand)
+}
+
+func (repeatField *repeatField) valueWith(rnd *rand.Rand) interface{} {
+	value := make([]interface{}, repeatField.count(rnd))
+	for i := range value {
+		value[i] = fieldValue(repeatField.item, rnd)
+	}
+
+	return value
+}
+
+// count picks a number of items
+func (repeatField *repeatField) count(rnd *rand.Rand) int {
+	n := repeatField.min
+	if repeatField.max > repeatField.min {
+		n += rnd.Intn(repeatField.max - repeatField.min + 1)
+	}
+
+	return n
+}
+
+const repeatMaxCount = 10000
+
+// newRepeatField handles an array directive: ["_go:repeat(count)", item] or ["_go:repeat(min, max)", item]
+func newRepeatField(name string, call *KeywordCall, item ParsedField) (ParsedField, error) {
+	if err := call.Arity(1, 2); err != nil {
+		return nil, err
+	}
+
+	bounds := make([]int64, len(call.Args))
+	for i := range call.Args {
+		bound, err := call.Int(i)
+		if err != nil {
+			return nil, err
+		}
+
+		if bound < 0 || bound > repeatMaxCount {
+			return nil, fmt.Errorf("keyword %s: count must be within [0, %d]", call.Keyword, repeatMaxCount)
+		}
+		bounds[i] = bound
+	}
+
+	min, max := bounds[0], bounds[len(bounds)-1]
+	if min > max {
+		return nil, fmt.Errorf("keyword %s: min %d is greater than max %d", call.Keyword, min, max)
+	}
+
+	return &repeatField{
+		name: name,
+		min:  int(min),
+		max:  int(max),
+		item: item,
+	}, nil
+}
+
 type referenceField struct {
 	name        string
 	referenceTo string
@@ -46,6 +274,14 @@ func (referenceField *referenceField) Value() interface{} {
 	return referenceField.referenceTo
 }
 
+func (referenceField *referenceField) dependencies() []string {
+	return []string{referenceField.referenceTo}
+}
+
+func (referenceField *referenceField) valueFrom(values []interface{}) interface{} {
+	return values[0]
+}
+
 func newReferenceField(name string, referenceTo string) ParsedField {
 	return &referenceField{
 		name:        name,
@@ -53,40 +289,162 @@ func newReferenceField(name string, referenceTo string) ParsedField {
 	}
 }
 
-type timestampField struct {
+// uuidField generates random (version 4) uuids
+type uuidField struct {
 	name string
 }
 
-func (timestampField *timestampField) Name() string {
-	return timestampField.name
+func (uuidField *uuidField) Name() string {
+	return uuidField.name
 }
 
-func (timestampField *timestampField) Value() interface{} {
-	return time.Now().Unix()
+func (uuidField *uuidField) Value() interface{} {
+	return uuidField.valueWith(fallbackRand)
 }
 
-func newTimestampField(name, _ string) (ParsedField, error) {
-	return &timestampField{
-		name: name,
-	}, nil
+func (uuidField *uuidField) valueWith(rnd *rand.Rand) interface{} {
+	return newUUIDv4(rnd).String()
 }
 
-type uuidField struct {
+func (uuidField *uuidField) appendJSON(enc *encoder) {
+	enc.buf = append(newUUIDv4(enc.rnd).appendTo(append(enc.buf, '"')), '"')
+}
+
+// uuidV7Field generates time-ordered (version 7) uuids.
+// Uuids of the same millisecond are kept sorted with a sequence that starts at a random value
+type uuidV7Field struct {
 	name string
+
+	clock Clock // a source of the current time, nil for the system one
+
+	mu         sync.Mutex
+	lastMillis int64
+	seq        uint16
 }
 
-func (uuidField *uuidField) Name() string {
-	return uuidField.name
+func (uuidV7Field *uuidV7Field) Name() string {
+	return uuidV7Field.name
 }
 
-func (uuidField *uuidField) Value() interface{} {
-	return time.Now().Unix()
+func (uuidV7Field *uuidV7Field) Value() interface{} {
+	return uuidV7Field.valueWith(fallbackRand)
 }
 
-func newUUIDField(name, _ string) (ParsedField, error) {
-	return &uuidField{
-		name: name,
-	}, nil
+func (uuidV7Field *uuidV7Field) valueWith(rnd *rand.Rand) interface{} {
+	return uuidV7Field.next(rnd).String()
+}
+
+func (uuidV7Field *uuidV7Field) appendJSON(enc *encoder) {
+	enc.buf = append(uuidV7Field.next(enc.rnd).appendTo(append(enc.buf, '"')), '"')
+}
+
+func (uuidV7Field *uuidV7Field) next(rnd *rand.Rand) uuid {
+	uuidV7Field.mu.Lock()
+	defer uuidV7Field.mu.Unlock()
+
+	millis := clockNow(uuidV7Field.clock).UnixMilli()
+	switch {
+	case millis > uuidV7Field.lastMillis:
+		uuidV7Field.lastMillis = millis
+		uuidV7Field.seq = uint16(rnd.Intn(uuidV7SeqMax / 2))
+	case uuidV7Field.seq < uuidV7SeqMax:
+		uuidV7Field.seq++
+	default:
+		uuidV7Field.lastMillis++
+		uuidV7Field.seq = 0
+	}
+
+	return newUUIDv7(rnd, uuidV7Field.lastMillis, uuidV7Field.seq)
+}
+
+func (uuidV7Field *uuidV7Field) useClock(clock Clock) {
+	uuidV7Field.clock = clock
+}
+
+const uuidV7SeqMax = 0x0fff
+
+// uuidV5Field generates name-based (version 5) uuids from the value of another field
+type uuidV5Field struct {
+	name      string
+	namespace uuid
+	from      string
+}
+
+func (uuidV5Field *uuidV5Field) Name() string {
+	return uuidV5Field.name
+}
+
+func (uuidV5Field *uuidV5Field) Value() interface{} {
+	return uuidV5Field.valueFrom([]interface{}{nil})
+}
+
+func (uuidV5Field *uuidV5Field) dependencies() []string {
+	return []string{uuidV5Field.from}
+}
+
+func (uuidV5Field *uuidV5Field) valueFrom(values []interface{}) interface{} {
+	name, ok := values[0].(string)
+	if !ok && values[0] != nil {
+		name = fmt.Sprint(values[0])
+	}
+
+	return newUUIDv5(uuidV5Field.namespace, name).String()
+}
+
+// newUUIDField handles uuid keywords:
+// "uuid" and "uuid:v4" for random uuids, "uuid:v7" for time-ordered ones
+// and "uuid:v5:_field" or "uuid:v5:namespace:_field" for name-based ones,
+// where namespace is one of dns (default), url, oid or x500
+func newUUIDField(name string, call *KeywordCall) (ParsedField, error) {
+	if err := call.Arity(0, 3); err != nil {
+		return nil, err
+	}
+
+	version := "v4"
+	if len(call.Args) > 0 {
+		var err error
+		if version, err = call.String(0); err != nil {
+			return nil, err
+		}
+	}
+
+	n := len(call.Args)
+	switch {
+	case version == "v4" && n <= 1:
+		return &uuidField{
+			name: name,
+		}, nil
+	case version == "v7" && n == 1:
+		return &uuidV7Field{
+			name: name,
+		}, nil
+	case version == "v5" && (n == 2 || n == 3):
+		namespace := uuidNamespaceDNS
+		if n == 3 {
+			rawNamespace, err := call.String(1)
+			if err != nil {
+				return nil, err
+			}
+
+			var ok bool
+			if namespace, ok = uuidNamespaces[rawNamespace]; !ok {
+				return nil, fmt.Errorf("unknown uuid namespace: %s", rawNamespace)
+			}
+		}
+
+		from, err := call.String(n - 1)
+		if err != nil || !strings.HasPrefix(from, referencePrefix) {
+			return nil, errors.New("uuid:v5 expects a reference to a field as the last argument")
+		}
+
+		return &uuidV5Field{
+			name:      name,
+			namespace: namespace,
+			from:      from[len(referencePrefix):],
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid uuid keyword: %s", call.Raw)
+	}
 }
 
 type patternField struct {
@@ -99,7 +457,16 @@ func (patternField *patternField) Name() string {
 }
 
 func (patternField *patternField) Value() interface{} {
-	return patternField.pattern.Generate()
+	return patternField.valueWith(fallbackRand)
+}
+
+func (patternField *patternField) valueWith(rnd *rand.Rand) interface{} {
+	return patternField.pattern.Generate(rnd)
+}
+
+func (patternField *patternField) appendJSON(enc *encoder) {
+	enc.scratch = patternField.pattern.Append(enc.scratch[:0], enc.rnd)
+	enc.appendScratch()
 }
 
 func newPatternField(name, raw string) (ParsedField, error) {
@@ -0,0 +1,196 @@
+package goson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+const (
+	interpolationOpen  = "${"
+	interpolationClose = '}'
+)
+
+// templateField concatenates pieces of a string value: literal text, pattern blocks,
+// keyword calls and references, e.g. "${_first}.${_last}@<4>.com" or "order-${int(1, 999)}"
+type templateField struct {
+	name   string
+	pieces []ParsedField
+	deps   []int // a number of dependencies of every piece
+}
+
+func (templateField *templateField) Name() string {
+	return templateField.name
+}
+
+// Value renders references as they are written in a template
+func (templateField *templateField) Value() interface{} {
+	draft := templateField.valueWith(fallbackRand).([]string)
+	for i, piece := range templateField.pieces {
+		if reference, ok := piece.(*referenceField); ok {
+			draft[i] = interpolationOpen + referencePrefix + reference.referenceTo + string(interpolationClose)
+		}
+	}
+
+	return strings.Join(draft, "")
+}
+
+// valueWith generates a draft: values of independent pieces, the dependent ones are left empty
+func (templateField *templateField) valueWith(rnd *rand.Rand) interface{} {
+	draft := make([]string, len(templateField.pieces))
+	for i, piece := range templateField.pieces {
+		if templateField.deps[i] == 0 {
+			draft[i] = interpolate(fieldValue(piece, rnd))
+		}
+	}
+
+	return draft
+}
+
+func (templateField *templateField) dependencies() []string {
+	var dependencies []string
+	for _, piece := range templateField.pieces {
+		if piece, ok := piece.(dependentField); ok {
+			dependencies = append(dependencies, piece.dependencies()...)
+		}
+	}
+
+	return dependencies
+}
+
+func (templateField *templateField) valueFrom(values []interface{}) interface{} {
+	return templateField.complete(templateField.valueWith(fallbackRand), values)
+}
+
+// complete fills dependent pieces of a draft and joins them
+func (templateField *templateField) complete(draft interface{}, values []interface{}) interface{} {
+	pieces, ok := draft.([]string)
+	if !ok {
+		return draft
+	}
+
+	for i, piece := range templateField.pieces {
+		n := templateField.deps[i]
+		if n == 0 {
+			continue
+		}
+
+		pieces[i] = interpolate(piece.(dependentField).valueFrom(values[:n]))
+		values = values[n:]
+	}
+
+	return strings.Join(pieces, "")
+}
+
+func newTemplateField(name string, pieces []ParsedField) *templateField {
+	deps := make([]int, len(pieces))
+	for i, piece := range pieces {
+		if piece, ok := piece.(dependentField); ok {
+			deps[i] = len(piece.dependencies())
+		}
+	}
+
+	return &templateField{
+		name:   name,
+		pieces: pieces,
+		deps:   deps,
+	}
+}
+
+// interpolate formats a generated value as a part of a string.
+// Objects and arrays are written as JSON, null is written as an empty string
+func interpolate(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case *orderedObject, []interface{}, map[string]interface{}:
+		b, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+
+		return string(b)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// templateToken is a piece of a template string: literal text with optional pattern blocks,
+// or an expression written in ${...}
+type templateToken struct {
+	text string
+	expr bool
+}
+
+// hasInterpolation reports whether a directive is a template string.
+// Escaped expressions count too, so "\${_first}" is unescaped into a literal text
+func hasInterpolation(value string) bool {
+	return strings.Contains(value, interpolationOpen)
+}
+
+// splitTemplate splits a directive into text and ${...} expressions.
+// Braces and quotes inside an expression are balanced, so "${oneof({\"a\": 1})}" is a single expression.
+// Escapes are kept in text tokens, they are handled by pattern blocks and unescapeString
+func splitTemplate(value string) ([]templateToken, error) {
+	var tokens []templateToken
+	var start int
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\':
+			i++
+		case strings.HasPrefix(value[i:], interpolationOpen):
+			if i > start {
+				tokens = append(tokens, templateToken{text: value[start:i]})
+			}
+
+			exprStart := i + len(interpolationOpen)
+			end := closingBrace(value[exprStart:])
+			if end == -1 {
+				return nil, fmt.Errorf("unclosed %s in %s", interpolationOpen, value)
+			}
+
+			expr := strings.TrimSpace(value[exprStart : exprStart+end])
+			if expr == "" {
+				return nil, errors.New("empty interpolation")
+			}
+
+			tokens = append(tokens, templateToken{text: expr, expr: true})
+			i = exprStart + end
+			start = i + 1
+		}
+	}
+
+	if start < len(value) {
+		tokens = append(tokens, templateToken{text: value[start:]})
+	}
+
+	return tokens, nil
+}
+
+// closingBrace returns the index of the brace closing an expression, or -1
+func closingBrace(raw string) int {
+	var depth int
+	var quoted bool
+	for i := 0; i < len(raw); i++ {
+		switch char := raw[i]; {
+		case quoted && char == '\\':
+			i++
+		case char == '"':
+			quoted = !quoted
+		case quoted:
+			continue
+		case char == '{':
+			depth++
+		case char == interpolationClose && depth == 0:
+			return i
+		case char == interpolationClose:
+			depth--
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,184 @@
+package goson
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_splitTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []templateToken
+		wantErr bool
+	}{
+		{
+			name:  "positive references and pattern",
+			input: "${_first}.${_last}@<4>.com",
+			want: []templateToken{
+				{text: "_first", expr: true},
+				{text: "."},
+				{text: "_last", expr: true},
+				{text: "@<4>.com"},
+			},
+		},
+		{
+			name:  "positive keyword with braces",
+			input: `id-${ int(1, 9) }-${oneof({"a": "}"})}`,
+			want: []templateToken{
+				{text: "id-"},
+				{text: "int(1, 9)", expr: true},
+				{text: "-"},
+				{text: `oneof({"a": "}"})`, expr: true},
+			},
+		},
+		{
+			name:  "positive escaped expression",
+			input: `\${_first}-${_last}`,
+			want: []templateToken{
+				{text: `\${_first}-`},
+				{text: "_last", expr: true},
+			},
+		},
+		{
+			name:    "unclosed expression",
+			input:   "${_first",
+			wantErr: true,
+		},
+		{
+			name:    "empty expression",
+			input:   "a${ }b",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitTemplate(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_interpolate(t *testing.T) {
+	object := newOrderedObject(1)
+	object.set("a", json.Number("1"))
+
+	assert.Equal(t, "", interpolate(nil))
+	assert.Equal(t, "text", interpolate("text"))
+	assert.Equal(t, "12", interpolate(int64(12)))
+	assert.Equal(t, "true", interpolate(true))
+	assert.Equal(t, `{"a":1}`, interpolate(object))
+	assert.Equal(t, `[1,"b"]`, interpolate([]interface{}{1, "b"}))
+}
+
+func Test_processor_Generate_interpolation(t *testing.T) {
+	body := []byte(`
+		{
+			"first": "_go:<5:lower>",
+			"last": "_go:<7:lower>",
+			"email": "_go:${_first}.${_last}@<4:lower>.com",
+			"login": "_go:_email",
+			"order": "_go:order-${int(1, 9)}-<2:upper>",
+			"plain": "_go:\\${_first}",
+			"items": [
+				"_go:repeat(2, 5)",
+				{"label": "_go:${_first}-<6:digit>"}
+			]
+		}
+	`)
+	testProcessor, err := New(body, WithSeed(3))
+	require.NoError(t, err)
+
+	for i := 0; i < 50; i++ {
+		var got struct {
+			First string `json:"first"`
+			Last  string `json:"last"`
+			Email string `json:"email"`
+			Login string `json:"login"`
+			Order string `json:"order"`
+			Plain string `json:"plain"`
+			Items []struct {
+				Label string `json:"label"`
+			} `json:"items"`
+		}
+		require.NoError(t, json.Unmarshal(testProcessor.Generate(), &got))
+
+		assert.Regexp(t, "^"+got.First+`\.`+got.Last+"@[a-z]{4}\\.com$", got.Email)
+		assert.Equal(t, got.Email, got.Login)
+		assert.Regexp(t, "^order-[1-9]-[A-Z]{2}$", got.Order)
+		assert.Equal(t, "${_first}", got.Plain)
+
+		labels := make(map[string]bool)
+		for _, item := range got.Items {
+			assert.Regexp(t, "^"+got.First+"-[0-9]{6}$", item.Label)
+			labels[item.Label] = true
+		}
+		assert.Len(t, labels, len(got.Items))
+	}
+}
+
+func TestNew_interpolationErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		check func(t *testing.T, err error)
+	}{
+		{
+			name:  "unknown reference",
+			input: `{"email": "_go:${_missing}@x.com"}`,
+			check: func(t *testing.T, err error) {
+				var referenceError *ReferenceError
+				assert.True(t, errors.As(err, &referenceError))
+			},
+		},
+		{
+			name:  "unknown keyword",
+			input: `{"email": "_go:${nope(1)}@x.com"}`,
+			check: func(t *testing.T, err error) {
+				var unknownKeywordError *UnknownKeywordError
+				require.True(t, errors.As(err, &unknownKeywordError))
+				assert.Equal(t, "nope", unknownKeywordError.Keyword)
+			},
+		},
+		{
+			name:  "invalid keyword arguments",
+			input: `{"n": "_go:n-${int(5, 1)}"}`,
+			check: func(t *testing.T, err error) {
+				var keywordError *KeywordError
+				assert.True(t, errors.As(err, &keywordError))
+			},
+		},
+		{
+			name:  "unclosed expression",
+			input: `{"n": "_go:n-${int(5, 1)"}`,
+			check: func(t *testing.T, err error) {
+				var syntaxError *SyntaxError
+				assert.True(t, errors.As(err, &syntaxError))
+			},
+		},
+		{
+			name:  "reference cycle",
+			input: `{"a": "_go:${_b}", "b": "_go:x${_a}"}`,
+			check: func(t *testing.T, err error) {
+				var referenceError *ReferenceError
+				assert.True(t, errors.As(err, &referenceError))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New([]byte(tt.input))
+			require.Error(t, err)
+			tt.check(t, err)
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package goson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeywordFunc signature for a keyword fields that take arguments.
+// It receives a parsed keyword call, e.g. for "_go:int(1, 100)" the keyword is "int"
+// and the arguments are 1 and 100
+type KeywordFunc func(key string, call *KeywordCall) (ParsedField, error)
+
+// KeywordCall is a parsed keyword directive.
+// Arguments are written either in parentheses: "int(1, 100)",
+// or separated by colons: "uuid:v7".
+// Each argument is a JSON value: a number, a string, a boolean, null, an array or an object.
+// Bare words like ms or v7 are taken as strings.
+type KeywordCall struct {
+	Keyword string
+	Args    []interface{}
+	Raw     string // directive as it was written in a template, without the prefix
+
+	rawArgs []string // arguments as they were written, without the surrounding spaces
+}
+
+// Arity checks that the number of arguments is within [min, max]. Negative max means no upper bound
+func (call *KeywordCall) Arity(min, max int) error {
+	n := len(call.Args)
+	switch {
+	case min == max && n != min:
+		return fmt.Errorf("keyword %s expects %d arguments, got %d", call.Keyword, min, n)
+	case n < min:
+		return fmt.Errorf("keyword %s expects at least %d arguments, got %d", call.Keyword, min, n)
+	case max >= 0 && n > max:
+		return fmt.Errorf("keyword %s expects at most %d arguments, got %d", call.Keyword, max, n)
+	}
+
+	return nil
+}
+
+// Number returns the i-th argument as a number literal, as it was written in a template
+func (call *KeywordCall) Number(i int) (json.Number, error) {
+	num, ok := call.arg(i).(json.Number)
+	if !ok {
+		return "", call.argError(i, "a number")
+	}
+
+	return num, nil
+}
+
+// Int returns the i-th argument as an integer
+func (call *KeywordCall) Int(i int) (int64, error) {
+	num, ok := call.arg(i).(json.Number)
+	if !ok {
+		return 0, call.argError(i, "an integer")
+	}
+
+	value, err := num.Int64()
+	if err != nil {
+		return 0, call.argError(i, "an integer")
+	}
+
+	return value, nil
+}
+
+// Float returns the i-th argument as a floating point number
+func (call *KeywordCall) Float(i int) (float64, error) {
+	num, ok := call.arg(i).(json.Number)
+	if !ok {
+		return 0, call.argError(i, "a number")
+	}
+
+	value, err := num.Float64()
+	if err != nil {
+		return 0, call.argError(i, "a number")
+	}
+
+	return value, nil
+}
+
+// String returns the i-th argument as a string
+func (call *KeywordCall) String(i int) (string, error) {
+	value, ok := call.arg(i).(string)
+	if !ok {
+		return "", call.argError(i, "a string")
+	}
+
+	return value, nil
+}
+
+// Bool returns the i-th argument as a boolean
+func (call *KeywordCall) Bool(i int) (bool, error) {
+	value, ok := call.arg(i).(bool)
+	if !ok {
+		return false, call.argError(i, "a boolean")
+	}
+
+	return value, nil
+}
+
+func (call *KeywordCall) arg(i int) interface{} {
+	if i < 0 || i >= len(call.Args) {
+		return nil
+	}
+
+	return call.Args[i]
+}
+
+// rawArg returns the i-th argument as it was written, or an empty string for a call built without a template
+func (call *KeywordCall) rawArg(i int) string {
+	if i < 0 || i >= len(call.rawArgs) {
+		return ""
+	}
+
+	return call.rawArgs[i]
+}
+
+func (call *KeywordCall) argError(i int, expected string) error {
+	if i < 0 || i >= len(call.Args) {
+		return fmt.Errorf("keyword %s: argument %d is missing, expected %s", call.Keyword, i+1, expected)
+	}
+
+	return fmt.Errorf("keyword %s: argument %d must be %s, got %v", call.Keyword, i+1, expected, call.Args[i])
+}
+
+const (
+	keywordArgsOpen      = '('
+	keywordArgsClose     = ')'
+	keywordArgsDelimiter = ','
+)
+
+// keywordName cuts off keyword arguments, so "uuid:v7" and "int(1, 100)"
+// are handled by the "uuid" and "int" keywords
+func keywordName(value string) string {
+	if i := strings.IndexAny(value, keywordArgSeparator+string(keywordArgsOpen)); i != -1 {
+		return value[:i]
+	}
+
+	return value
+}
+
+// parseKeywordCall splits a directive into a keyword and its arguments
+func parseKeywordCall(value string) (*KeywordCall, error) {
+	call := &KeywordCall{
+		Keyword: keywordName(value),
+		Raw:     value,
+	}
+
+	rest := value[len(call.Keyword):]
+	var rawArgs []string
+	switch {
+	case rest == "":
+		return call, nil
+	case rest[0] == keywordArgsOpen:
+		if rest[len(rest)-1] != keywordArgsClose {
+			return nil, fmt.Errorf("keyword %s: unclosed argument list", call.Keyword)
+		}
+
+		var err error
+		rawArgs, err = splitKeywordArgs(rest[1 : len(rest)-1])
+		if err != nil {
+			return nil, fmt.Errorf("keyword %s: %v", call.Keyword, err)
+		}
+	default:
+		rawArgs = strings.Split(rest[len(keywordArgSeparator):], keywordArgSeparator)
+	}
+
+	for i, rawArg := range rawArgs {
+		arg, err := parseKeywordArg(rawArg)
+		if err != nil {
+			return nil, fmt.Errorf("keyword %s: argument %d: %v", call.Keyword, i+1, err)
+		}
+		call.Args = append(call.Args, arg)
+		call.rawArgs = append(call.rawArgs, strings.TrimSpace(rawArg))
+	}
+
+	return call, nil
+}
+
+// splitKeywordArgs splits an argument list by the top-level commas,
+// skipping the ones inside quotes, brackets and braces
+func splitKeywordArgs(raw string) ([]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var args []string
+	var depth, start int
+	var quoted bool
+	for i := 0; i < len(raw); i++ {
+		switch char := raw[i]; {
+		case quoted && char == '\\':
+			i++
+		case char == '"':
+			quoted = !quoted
+		case quoted:
+			continue
+		case char == '(' || char == '[' || char == '{':
+			depth++
+		case char == ')' || char == ']' || char == '}':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unexpected %c", char)
+			}
+		case char == keywordArgsDelimiter && depth == 0:
+			args = append(args, raw[start:i])
+			start = i + 1
+		}
+	}
+
+	if quoted || depth != 0 {
+		return nil, errors.New("unbalanced quotes or brackets")
+	}
+
+	return append(args, raw[start:]), nil
+}
+
+// parseKeywordArg decodes a single argument as a JSON value or takes it as a bare word
+func parseKeywordArg(raw string) (interface{}, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, errors.New("empty argument")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	decoder.UseNumber()
+
+	var arg interface{}
+	err := decoder.Decode(&arg)
+	if err == nil && !decoder.More() {
+		return arg, nil
+	}
+
+	if strings.ContainsAny(raw[:1], `"[{`) {
+		return nil, fmt.Errorf("invalid value %s", raw)
+	}
+
+	return raw, nil
+}
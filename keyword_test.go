@@ -0,0 +1,145 @@
+package goson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseKeywordCall(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    *KeywordCall
+		wantErr bool
+	}{
+		{
+			name:  "positive without arguments",
+			input: "timestamp",
+			want: &KeywordCall{
+				Keyword: "timestamp",
+				Raw:     "timestamp",
+			},
+		},
+		{
+			name:  "positive empty argument list",
+			input: "timestamp()",
+			want: &KeywordCall{
+				Keyword: "timestamp",
+				Raw:     "timestamp()",
+			},
+		},
+		{
+			name:  "positive typed arguments",
+			input: `int(1, -2.5, "a, b", true, null, ms, [1, 2], {"a": "(b)"})`,
+			want: &KeywordCall{
+				Keyword: "int",
+				Args: []interface{}{
+					json.Number("1"),
+					json.Number("-2.5"),
+					"a, b",
+					true,
+					nil,
+					"ms",
+					[]interface{}{json.Number("1"), json.Number("2")},
+					map[string]interface{}{"a": "(b)"},
+				},
+				Raw:     `int(1, -2.5, "a, b", true, null, ms, [1, 2], {"a": "(b)"})`,
+				rawArgs: []string{"1", "-2.5", `"a, b"`, "true", "null", "ms", "[1, 2]", `{"a": "(b)"}`},
+			},
+		},
+		{
+			name:  "positive short form",
+			input: "uuid:v5:_email",
+			want: &KeywordCall{
+				Keyword: "uuid",
+				Args:    []interface{}{"v5", "_email"},
+				Raw:     "uuid:v5:_email",
+				rawArgs: []string{"v5", "_email"},
+			},
+		},
+		{
+			name:    "unclosed argument list",
+			input:   "int(1, 2",
+			wantErr: true,
+		},
+		{
+			name:    "empty argument",
+			input:   "int(1,)",
+			wantErr: true,
+		},
+		{
+			name:    "unbalanced brackets",
+			input:   "int([1, 2)",
+			wantErr: true,
+		},
+		{
+			name:    "unbalanced quotes",
+			input:   `int("1)`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json value",
+			input:   `int({"a":})`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseKeywordCall(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestKeywordCall_Arity(t *testing.T) {
+	call := &KeywordCall{
+		Keyword: "dummy",
+		Args:    []interface{}{json.Number("1"), "a"},
+	}
+
+	assert.NoError(t, call.Arity(2, 2))
+	assert.NoError(t, call.Arity(0, -1))
+	assert.EqualError(t, call.Arity(1, 1), "keyword dummy expects 1 arguments, got 2")
+	assert.EqualError(t, call.Arity(3, 4), "keyword dummy expects at least 3 arguments, got 2")
+	assert.EqualError(t, call.Arity(0, 1), "keyword dummy expects at most 1 arguments, got 2")
+}
+
+func TestKeywordCall_typedArgs(t *testing.T) {
+	call := &KeywordCall{
+		Keyword: "dummy",
+		Args:    []interface{}{json.Number("1"), json.Number("1.5"), "a", true},
+	}
+
+	i, err := call.Int(0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), i)
+
+	f, err := call.Float(1)
+	require.NoError(t, err)
+	assert.Equal(t, 1.5, f)
+
+	s, err := call.String(2)
+	require.NoError(t, err)
+	assert.Equal(t, "a", s)
+
+	b, err := call.Bool(3)
+	require.NoError(t, err)
+	assert.True(t, b)
+
+	_, err = call.Int(1)
+	assert.EqualError(t, err, "keyword dummy: argument 2 must be an integer, got 1.5")
+	_, err = call.Float(2)
+	assert.EqualError(t, err, "keyword dummy: argument 3 must be a number, got a")
+	_, err = call.String(0)
+	assert.EqualError(t, err, "keyword dummy: argument 1 must be a string, got 1")
+	_, err = call.Bool(4)
+	assert.EqualError(t, err, "keyword dummy: argument 5 is missing, expected a boolean")
+}
@@ -0,0 +1,332 @@
+package goson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// intField generates integers within [min, max]
+type intField struct {
+	name     string
+	min, max int64
+}
+
+func (intField *intField) Name() string {
+	return intField.name
+}
+
+func (intField *intField) Value() interface{} {
+	return intField.valueWith(fallbackRand)
+}
+
+func (intField *intField) valueWith(rnd *rand.Rand) interface{} {
+	return randomInt64(rnd, intField.min, intField.max)
+}
+
+func (intField *intField) appendJSON(enc *encoder) {
+	enc.buf = strconv.AppendInt(enc.buf, randomInt64(enc.rnd, intField.min, intField.max), 10)
+}
+
+// newIntField handles "int" keyword: "_go:int(max)" or "_go:int(min, max)", bounds are inclusive
+func newIntField(name string, call *KeywordCall) (ParsedField, error) {
+	if err := call.Arity(1, 2); err != nil {
+		return nil, err
+	}
+
+	bounds := make([]int64, len(call.Args))
+	for i := range call.Args {
+		bound, err := call.Int(i)
+		if err != nil {
+			return nil, err
+		}
+		bounds[i] = bound
+	}
+
+	field := &intField{
+		name: name,
+		max:  bounds[len(bounds)-1],
+	}
+	if len(bounds) == 2 {
+		field.min = bounds[0]
+	}
+
+	if field.min > field.max {
+		return nil, fmt.Errorf("keyword %s: min %d is greater than max %d", call.Keyword, field.min, field.max)
+	}
+
+	return field, nil
+}
+
+// floatField generates floating point numbers within [min, max].
+// Negative precision leaves them unrounded, otherwise the bounds are rounded inward to the precision
+type floatField struct {
+	name      string
+	min, max  float64
+	precision int
+}
+
+func (floatField *floatField) Name() string {
+	return floatField.name
+}
+
+func (floatField *floatField) Value() interface{} {
+	return floatField.valueWith(fallbackRand)
+}
+
+func (floatField *floatField) appendJSON(enc *encoder) {
+	enc.appendFloat(floatField.valueWith(enc.rnd).(float64))
+}
+
+func (floatField *floatField) valueWith(rnd *rand.Rand) interface{} {
+	// a weighted sum doesn't overflow as max-min does for wide bounds
+	r := rnd.Float64()
+	value := floatField.min*(1-r) + floatField.max*r
+	if floatField.precision < 0 {
+		return value
+	}
+
+	value = roundFloat(value, floatField.precision, math.Round)
+
+	return math.Max(floatField.min, math.Min(floatField.max, value))
+}
+
+// roundFloat rounds a value to a number of digits after the decimal point with round: math.Round, math.Ceil...
+// Values too large to be scaled have no digits after the decimal point and stay as they are
+func roundFloat(value float64, precision int, round func(float64) float64) float64 {
+	pow := math.Pow10(precision)
+	scaled := value * pow
+	if math.IsInf(scaled, 0) {
+		return value
+	}
+
+	// scaling is inexact, e.g. 0.3*10 is a bit more than 3, so a value on the grid stays as it is
+	if math.Round(scaled)/pow == value {
+		return value
+	}
+
+	return round(scaled) / pow
+}
+
+const floatMaxPrecision = 15
+
+// newFloatField handles "float" keyword: "_go:float(min, max)" or "_go:float(min, max, precision)",
+// where precision is a number of digits after the decimal point
+func newFloatField(name string, call *KeywordCall) (ParsedField, error) {
+	if err := call.Arity(2, 3); err != nil {
+		return nil, err
+	}
+
+	min, err := call.Float(0)
+	if err != nil {
+		return nil, err
+	}
+
+	max, err := call.Float(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if min > max {
+		return nil, fmt.Errorf("keyword %s: min %v is greater than max %v", call.Keyword, min, max)
+	}
+
+	precision := int64(-1)
+	if len(call.Args) == 3 {
+		if precision, err = call.Int(2); err != nil {
+			return nil, err
+		}
+
+		if precision < 0 || precision > floatMaxPrecision {
+			return nil, fmt.Errorf("keyword %s: precision must be within [0, %d]", call.Keyword, floatMaxPrecision)
+		}
+
+		// values are rounded to the precision, so the bounds must be on its grid
+		rawMin, rawMax := min, max
+		min, max = roundFloat(min, int(precision), math.Ceil), roundFloat(max, int(precision), math.Floor)
+		if min > max {
+			return nil, fmt.Errorf("keyword %s: no value with precision %d within [%v, %v]",
+				call.Keyword, precision, rawMin, rawMax)
+		}
+	}
+
+	return &floatField{
+		name:      name,
+		min:       min,
+		max:       max,
+		precision: int(precision),
+	}, nil
+}
+
+// decimalField generates fixed-point numbers with exactly scale digits after the decimal point.
+// Bounds are kept in units of 10^-scale, so there are no floating point errors.
+// Values are emitted as JSON numbers, or as strings for money-like fields
+type decimalField struct {
+	name     string
+	min, max int64
+	scale    int
+	asString bool
+}
+
+func (decimalField *decimalField) Name() string {
+	return decimalField.name
+}
+
+func (decimalField *decimalField) Value() interface{} {
+	return decimalField.valueWith(fallbackRand)
+}
+
+func (decimalField *decimalField) valueWith(rnd *rand.Rand) interface{} {
+	value := formatFixedPoint(randomInt64(rnd, decimalField.min, decimalField.max), decimalField.scale)
+	if decimalField.asString {
+		return value
+	}
+
+	return json.Number(value)
+}
+
+func (decimalField *decimalField) appendJSON(enc *encoder) {
+	units := randomInt64(enc.rnd, decimalField.min, decimalField.max)
+	if decimalField.asString {
+		enc.buf = append(appendFixedPoint(append(enc.buf, '"'), units, decimalField.scale), '"')
+		return
+	}
+
+	enc.buf = appendFixedPoint(enc.buf, units, decimalField.scale)
+}
+
+const decimalMaxScale = 18
+
+// newDecimalField handles "decimal" keyword: "_go:decimal(min, max, scale)"
+// or "_go:decimal(min, max, scale, string)" to emit values as strings
+func newDecimalField(name string, call *KeywordCall) (ParsedField, error) {
+	if err := call.Arity(3, 4); err != nil {
+		return nil, err
+	}
+
+	scale, err := call.Int(2)
+	if err != nil {
+		return nil, err
+	}
+
+	if scale < 0 || scale > decimalMaxScale {
+		return nil, fmt.Errorf("keyword %s: scale must be within [0, %d]", call.Keyword, decimalMaxScale)
+	}
+
+	field := &decimalField{
+		name:  name,
+		scale: int(scale),
+	}
+	for i, bound := range []*int64{&field.min, &field.max} {
+		num, err := call.Number(i)
+		if err != nil {
+			return nil, err
+		}
+
+		if *bound, err = parseFixedPoint(num.String(), field.scale); err != nil {
+			return nil, fmt.Errorf("keyword %s: argument %d: %v", call.Keyword, i+1, err)
+		}
+	}
+
+	if field.min > field.max {
+		return nil, fmt.Errorf("keyword %s: min %s is greater than max %s", call.Keyword, call.Args[0], call.Args[1])
+	}
+
+	if len(call.Args) == 4 {
+		format, err := call.String(3)
+		if err != nil || format != "string" {
+			return nil, fmt.Errorf("keyword %s: argument 4 must be string, got %v", call.Keyword, call.Args[3])
+		}
+		field.asString = true
+	}
+
+	return field, nil
+}
+
+// parseFixedPoint converts a decimal literal like "-12.5" into units of 10^-scale: -1250 for the scale 2
+func parseFixedPoint(raw string, scale int) (int64, error) {
+	if strings.ContainsAny(raw, "eE") {
+		return 0, fmt.Errorf("exponent is not supported: %s", raw)
+	}
+
+	whole, fraction := raw, ""
+	if i := strings.IndexByte(raw, '.'); i != -1 {
+		whole, fraction = raw[:i], raw[i+1:]
+	}
+
+	if len(strings.TrimRight(fraction, "0")) > scale {
+		return 0, fmt.Errorf("%s has more than %d digits after the decimal point", raw, scale)
+	}
+
+	if len(fraction) > scale {
+		fraction = fraction[:scale]
+	}
+
+	units, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", scale-len(fraction)), 10, 64)
+	if err != nil {
+		return 0, errors.New("value is out of range")
+	}
+
+	return units, nil
+}
+
+// formatFixedPoint converts units of 10^-scale back into a decimal literal
+func formatFixedPoint(units int64, scale int) string {
+	return string(appendFixedPoint(nil, units, scale))
+}
+
+// appendFixedPoint is formatFixedPoint appending to dst
+func appendFixedPoint(dst []byte, units int64, scale int) []byte {
+	abs := uint64(units)
+	if units < 0 {
+		dst = append(dst, '-')
+		abs = uint64(-units)
+	}
+
+	start := len(dst)
+	dst = strconv.AppendUint(dst, abs, 10)
+	if scale == 0 {
+		return dst
+	}
+
+	// pad with zeros to have at least one digit before the point
+	if digits := len(dst) - start; digits <= scale {
+		pad := scale - digits + 1
+		for i := 0; i < pad; i++ {
+			dst = append(dst, '0')
+		}
+		copy(dst[start+pad:], dst[start:start+digits])
+		for i := 0; i < pad; i++ {
+			dst[start+i] = '0'
+		}
+	}
+
+	point := len(dst) - scale
+	dst = append(dst, 0)
+	copy(dst[point+1:], dst[point:])
+	dst[point] = '.'
+
+	return dst
+}
+
+// randomInt64 returns a random integer within [min, max] without overflowing on wide ranges
+func randomInt64(rnd *rand.Rand, min, max int64) int64 {
+	span := uint64(max-min) + 1
+	switch {
+	case span == 0:
+		return int64(rnd.Uint64())
+	case span <= math.MaxInt64:
+		return min + rnd.Int63n(int64(span))
+	}
+
+	limit := math.MaxUint64 - math.MaxUint64%span
+	for {
+		if value := rnd.Uint64(); value < limit {
+			return min + int64(value%span)
+		}
+	}
+}
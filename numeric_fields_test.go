@@ -0,0 +1,266 @@
+package goson
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newIntField(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    ParsedField
+		wantErr bool
+	}{
+		{
+			name:  "positive max only",
+			input: "int(10)",
+			want:  &intField{name: "n", max: 10},
+		},
+		{
+			name:  "positive min and max",
+			input: "int(-5, 5)",
+			want:  &intField{name: "n", min: -5, max: 5},
+		},
+		{
+			name:    "invalid bounds",
+			input:   "int(5, 1)",
+			wantErr: true,
+		},
+		{
+			name:    "invalid type",
+			input:   "int(1.5)",
+			wantErr: true,
+		},
+		{
+			name:    "invalid arity",
+			input:   "int",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call, err := parseKeywordCall(tt.input)
+			require.NoError(t, err)
+			got, err := newIntField("n", call)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_newFloatField(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    ParsedField
+		wantErr bool
+	}{
+		{
+			name:  "positive without precision",
+			input: "float(0, 1)",
+			want:  &floatField{name: "n", max: 1, precision: -1},
+		},
+		{
+			name:  "positive with precision",
+			input: "float(-1.5, 1.5, 2)",
+			want:  &floatField{name: "n", min: -1.5, max: 1.5, precision: 2},
+		},
+		{
+			name:    "invalid bounds",
+			input:   "float(1, 0)",
+			wantErr: true,
+		},
+		{
+			name:  "bounds rounded inward to precision",
+			input: "float(0.14, 0.96, 1)",
+			want:  &floatField{name: "n", min: 0.2, max: 0.9, precision: 1},
+		},
+		{
+			name:  "wide bounds",
+			input: "float(-1.7e308, 1.7e308, 2)",
+			want:  &floatField{name: "n", min: -1.7e308, max: 1.7e308, precision: 2},
+		},
+		{
+			name:    "invalid precision",
+			input:   "float(0, 1, 16)",
+			wantErr: true,
+		},
+		{
+			name:    "no value with precision",
+			input:   "float(0.1, 0.2, 0)",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call, err := parseKeywordCall(tt.input)
+			require.NoError(t, err)
+			got, err := newFloatField("n", call)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_floatField_valueWith(t *testing.T) {
+	rnd := newTestRand()
+	tests := []struct {
+		name  string
+		field *floatField
+		check func(value float64) bool
+	}{
+		{
+			name:  "wide bounds",
+			field: &floatField{min: -1.7e308, max: 1.7e308, precision: -1},
+			check: func(value float64) bool { return value >= -1.7e308 && value <= 1.7e308 },
+		},
+		{
+			name:  "wide bounds with precision",
+			field: &floatField{min: -math.MaxFloat64, max: math.MaxFloat64, precision: 2},
+			check: func(value float64) bool { return !math.IsInf(value, 0) },
+		},
+		{
+			name:  "precision",
+			field: &floatField{min: 0.3, max: 0.5, precision: 1},
+			check: func(value float64) bool { return value == 0.3 || value == 0.4 || value == 0.5 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 1000; i++ {
+				value := tt.field.valueWith(rnd).(float64)
+				require.True(t, tt.check(value), value)
+			}
+		})
+	}
+}
+
+func Test_newDecimalField(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    ParsedField
+		wantErr bool
+	}{
+		{
+			name:  "positive",
+			input: "decimal(0.5, 100, 2)",
+			want:  &decimalField{name: "n", min: 50, max: 10000, scale: 2},
+		},
+		{
+			name:  "positive as string",
+			input: "decimal(-1, 1.25, 3, string)",
+			want:  &decimalField{name: "n", min: -1000, max: 1250, scale: 3, asString: true},
+		},
+		{
+			name:    "invalid bound scale",
+			input:   "decimal(0, 1.255, 2)",
+			wantErr: true,
+		},
+		{
+			name:    "invalid format",
+			input:   "decimal(0, 1, 2, int)",
+			wantErr: true,
+		},
+		{
+			name:    "invalid bounds",
+			input:   "decimal(1, 0, 2)",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call, err := parseKeywordCall(tt.input)
+			require.NoError(t, err)
+			got, err := newDecimalField("n", call)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_formatFixedPoint(t *testing.T) {
+	tests := []struct {
+		units int64
+		scale int
+		want  string
+	}{
+		{units: 12345, scale: 2, want: "123.45"},
+		{units: 5, scale: 3, want: "0.005"},
+		{units: -50, scale: 2, want: "-0.50"},
+		{units: 42, scale: 0, want: "42"},
+		{units: math.MinInt64, scale: 0, want: "-9223372036854775808"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, formatFixedPoint(tt.units, tt.scale))
+		})
+	}
+}
+
+func Test_randomInt64(t *testing.T) {
+	rnd := newTestRand()
+	for i := 0; i < 1000; i++ {
+		got := randomInt64(rnd, -3, 3)
+		assert.GreaterOrEqual(t, got, int64(-3))
+		assert.LessOrEqual(t, got, int64(3))
+	}
+
+	assert.Equal(t, int64(7), randomInt64(rnd, 7, 7))
+	assert.NotPanics(t, func() { randomInt64(rnd, math.MinInt64, math.MaxInt64) })
+	assert.NotPanics(t, func() { randomInt64(rnd, -1, math.MaxInt64) })
+}
+
+func Test_processor_Generate_numeric(t *testing.T) {
+	body := []byte(`
+		{
+			"count": "_go:int(1, 10)",
+			"ratio": "_go:float(0, 1, 2)",
+			"wide": "_go:float(-1.7e308, 1.7e308)",
+			"price": "_go:decimal(0, 100, 2)",
+			"amount": "_go:decimal(0, 100, 2, string)"
+		}
+	`)
+	testProcessor, err := New(body, WithSeed(1))
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		var got map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(testProcessor.Generate()))
+		decoder.UseNumber()
+		require.NoError(t, decoder.Decode(&got))
+
+		count, err := got["count"].(json.Number).Int64()
+		require.NoError(t, err)
+		assert.True(t, count >= 1 && count <= 10)
+
+		ratio, err := got["ratio"].(json.Number).Float64()
+		require.NoError(t, err)
+		assert.True(t, ratio >= 0 && ratio <= 1)
+
+		wide, err := got["wide"].(json.Number).Float64()
+		require.NoError(t, err)
+		assert.True(t, wide >= -1.7e308 && wide <= 1.7e308)
+
+		assert.Regexp(t, `^\d{1,3}\.\d{2}$`, got["price"].(json.Number).String())
+		assert.Regexp(t, `^\d{1,3}\.\d{2}$`, got["amount"].(string))
+	}
+}
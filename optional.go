@@ -0,0 +1,120 @@
+package goson
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+const (
+	// optionalMark starts a presence suffix of a key: "nickname?0.3" is present in 30% of records
+	optionalMark = '?'
+	// nullableMark is the doubled optionalMark: "nickname??0.3" is null in 70% of records instead of being omitted
+	nullableMark = "??"
+)
+
+// presence is a chance of an optional field to be generated
+type presence struct {
+	probability float64
+	nullable    bool // write null instead of omitting the key
+}
+
+// optionalField is a field of an object that is generated only with a probability,
+// otherwise its key is omitted or, in the nullable mode, its value is null
+type optionalField struct {
+	field    ParsedField
+	presence presence
+}
+
+func (optionalField *optionalField) Name() string {
+	return optionalField.field.Name()
+}
+
+func (optionalField *optionalField) Value() interface{} {
+	return optionalField.valueWith(fallbackRand)
+}
+
+// valueWith generates a value of a present field and nil otherwise
+func (optionalField *optionalField) valueWith(rnd *rand.Rand) interface{} {
+	value, _ := optionalField.entryWith(rnd)
+
+	return value
+}
+
+// entryWith generates a value of the field and reports whether its key is written into a record
+func (optionalField *optionalField) entryWith(rnd *rand.Rand) (interface{}, bool) {
+	if optionalField.present(rnd) {
+		return fieldValue(optionalField.field, rnd), true
+	}
+
+	return nil, optionalField.presence.nullable
+}
+
+// present draws whether the field is generated, it always draws, so the presence doesn't shift the randomness
+func (optionalField *optionalField) present(rnd *rand.Rand) bool {
+	return rnd.Float64() < optionalField.presence.probability
+}
+
+func newOptionalField(field ParsedField, presence presence) ParsedField {
+	return &optionalField{
+		field:    field,
+		presence: presence,
+	}
+}
+
+// entryValue generates a value of an object entry and reports whether the entry is written
+func entryValue(field ParsedField, rnd *rand.Rand) (interface{}, bool) {
+	if field, ok := field.(*optionalField); ok {
+		return field.entryWith(rnd)
+	}
+
+	return fieldValue(field, rnd), true
+}
+
+// unwrapOptional returns a field wrapped into an optionalField or the field itself
+func unwrapOptional(field ParsedField) ParsedField {
+	if field, ok := field.(*optionalField); ok {
+		return field.field
+	}
+
+	return field
+}
+
+// splitOptionalKey splits a template key into a name and a presence suffix:
+// "nickname?0.3" and "nickname??0.3". The probability is required, so keys like "done?" stay as they are.
+// A backslash before the suffix keeps it in the name: "rate\\?0.5" is a key "rate?0.5"
+func splitOptionalKey(key string) (string, *presence, error) {
+	i := strings.LastIndexByte(key, optionalMark)
+	if i == -1 || !isProbability(key[i+1:]) {
+		return key, nil, nil
+	}
+
+	name, nullable := key[:i], strings.HasSuffix(key[:i+1], nullableMark)
+	if nullable {
+		name = key[:i-1]
+	}
+
+	if strings.HasSuffix(name, string(prefixEscape)) {
+		return name[:len(name)-1] + key[len(name):], nil, nil
+	}
+
+	probability, err := strconv.ParseFloat(key[i+1:], 64)
+	if err != nil || probability > 1 {
+		return "", nil, fmt.Errorf("presence probability of %s must be within [0, 1]: %s", name, key[i+1:])
+	}
+
+	return name, &presence{
+		probability: probability,
+		nullable:    nullable,
+	}, nil
+}
+
+// isProbability reports whether a suffix looks like a probability: digits with an optional dot
+func isProbability(raw string) bool {
+	if raw == "" || raw == "." {
+		return false
+	}
+
+	return strings.Trim(raw, "0123456789.") == ""
+}
@@ -0,0 +1,204 @@
+package goson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_splitOptionalKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		want     string
+		presence *presence
+		wantErr  bool
+	}{
+		{
+			name: "plain key",
+			key:  "nickname",
+			want: "nickname",
+		},
+		{
+			name:     "optional",
+			key:      "nickname?0.3",
+			want:     "nickname",
+			presence: &presence{probability: 0.3},
+		},
+		{
+			name:     "nullable",
+			key:      "nickname??.25",
+			want:     "nickname",
+			presence: &presence{probability: 0.25, nullable: true},
+		},
+		{
+			name:     "always present",
+			key:      "a?b?1",
+			want:     "a?b",
+			presence: &presence{probability: 1},
+		},
+		{
+			name: "question without a probability",
+			key:  "done?",
+			want: "done?",
+		},
+		{
+			name: "not a probability",
+			key:  "why?not",
+			want: "why?not",
+		},
+		{
+			name: "escaped",
+			key:  `rate\?0.5`,
+			want: "rate?0.5",
+		},
+		{
+			name: "escaped nullable",
+			key:  `rate\??0.5`,
+			want: "rate??0.5",
+		},
+		{
+			name:    "greater than one",
+			key:     "nickname?1.5",
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			key:     "nickname?0.1.2",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, presence, err := splitOptionalKey(tt.key)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.presence, presence)
+		})
+	}
+}
+
+func Test_processor_Generate_optional(t *testing.T) {
+	body := []byte(`
+		{
+			"id": "_go:int(1, 9)",
+			"nickname?0.3": "_go:<6:lower>",
+			"bio??0.6": "_go:<10>",
+			"never?0": "static",
+			"profile?0.5": {"first?0.5": "_go:<4>", "last": "_go:<4>"}
+		}
+	`)
+
+	testProcessor, err := New(body, WithSeed(1))
+	require.NoError(t, err)
+	require.NotNil(t, testProcessor.(*processor).program)
+
+	const n = 2000
+	var nicknames, bios, profiles int
+	for i := 0; i < n; i++ {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(testProcessor.Generate(), &record))
+
+		assert.Contains(t, record, "id")
+		assert.NotContains(t, record, "never")
+		assert.Contains(t, record, "bio")
+		if _, ok := record["nickname"]; ok {
+			nicknames++
+		}
+		if record["bio"] != nil {
+			bios++
+		}
+		if profile, ok := record["profile"].(map[string]interface{}); ok {
+			profiles++
+			assert.Contains(t, profile, "last")
+		}
+	}
+
+	assert.InDelta(t, 0.3, float64(nicknames)/n, 0.05)
+	assert.InDelta(t, 0.6, float64(bios)/n, 0.05)
+	assert.InDelta(t, 0.5, float64(profiles)/n, 0.05)
+}
+
+func Test_processor_Generate_optionalCompiled(t *testing.T) {
+	body := []byte(`
+		{
+			"a?0.5": "_go:<3>",
+			"b??0.5": "_go:int(1, 9)",
+			"c?0.5": {"d?0.5": "_go:<2>", "e?0.5": "x"},
+			"items": ["_go:repeat(0, 3)", {"f?0.5": "_go:<2>", "g": 1}],
+			"h": "_go:uuid"
+		}
+	`)
+
+	compiled, err := New(body, WithSeed(3))
+	require.NoError(t, err)
+	require.NotNil(t, compiled.(*processor).program)
+
+	reference, err := New(body, WithSeed(3))
+	require.NoError(t, err)
+	reference.(*processor).program = nil
+
+	for i := 0; i < 200; i++ {
+		got := compiled.Generate()
+		assert.True(t, json.Valid(got), string(got))
+		assert.Equal(t, string(reference.Generate()), string(got))
+	}
+}
+
+func Test_processor_Generate_optionalReferences(t *testing.T) {
+	body := []byte(`
+		{
+			"password?0.5": "_go:<8>",
+			"confirm": "_go:_password",
+			"copy?0.5": "_go:_password",
+			"nullable??0.5": "_go:_password",
+			"greeting??0.5": "_go:hi ${_password}"
+		}
+	`)
+
+	testProcessor, err := New(body, WithSeed(2))
+	require.NoError(t, err)
+
+	var nulls, omitted int
+	for i := 0; i < 500; i++ {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(testProcessor.Generate(), &record))
+
+		assert.Equal(t, record["password"], record["confirm"])
+		if copied, ok := record["copy"]; ok {
+			assert.Equal(t, record["password"], copied)
+		} else {
+			omitted++
+		}
+
+		require.Contains(t, record, "nullable")
+		require.Contains(t, record, "greeting")
+		if record["nullable"] == nil {
+			nulls++
+		} else {
+			assert.Equal(t, record["password"], record["nullable"])
+		}
+		if record["greeting"] != nil {
+			assert.Equal(t, "hi "+interpolate(record["password"]), record["greeting"])
+		}
+	}
+
+	assert.Greater(t, nulls, 100)
+	assert.Greater(t, omitted, 100)
+}
+
+func TestNew_optionalErrors(t *testing.T) {
+	_, err := New([]byte(`{"nickname?1.5": "_go:<4>"}`))
+	var syntaxErr *SyntaxError
+	assert.ErrorAs(t, err, &syntaxErr)
+
+	_, err = New([]byte(`{"nickname?0.5": "_go:<4>", "copy": "_go:_nickname.first"}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,84 @@
+package goson
+
+import "time"
+
+// Option configures a Processor built by New
+type Option func(*options)
+
+type options struct {
+	seed       int64
+	keywordSet map[string]KeywordFunc
+	prefix     string
+	allErrors  bool
+	clock      Clock
+}
+
+// WithSeed makes a Processor generate the same sequence of records for the same seed.
+// Without it the seed is taken from the current time and can be read with Processor.Seed
+func WithSeed(seed int64) Option {
+	return func(options *options) {
+		options.seed = seed
+	}
+}
+
+// WithKeywords adds a custom set of keywords to the default one.
+// NewFieldFunc receives the whole directive, arguments included, as a value
+func WithKeywords(keywordSet map[string]NewFieldFunc) Option {
+	return func(options *options) {
+		for key, fn := range keywordSet {
+			options.keywordSet[key] = adaptNewFieldFunc(fn)
+		}
+	}
+}
+
+// WithKeywordFuncs adds a custom set of keywords that take parsed arguments to the default one
+func WithKeywordFuncs(keywordSet map[string]KeywordFunc) Option {
+	return func(options *options) {
+		for key, fn := range keywordSet {
+			options.keywordSet[key] = fn
+		}
+	}
+}
+
+// WithPrefix replaces the default "_go:" prefix of directives,
+// e.g. WithPrefix("$gen:") for templates that contain "_go:" as data
+func WithPrefix(prefix string) Option {
+	return func(options *options) {
+		options.prefix = prefix
+	}
+}
+
+// WithAllErrors makes New report every problem of a template at once as a *MultiError
+// instead of stopping at the first one
+func WithAllErrors() Option {
+	return func(options *options) {
+		options.allErrors = true
+	}
+}
+
+// WithClock makes timestamp and uuid:v7 keywords take the current time from clock instead of the system one,
+// see NewFixedClock, NewOffsetClock and NewAdvancingClock
+func WithClock(clock Clock) Option {
+	return func(options *options) {
+		options.clock = clock
+	}
+}
+
+func adaptNewFieldFunc(fn NewFieldFunc) KeywordFunc {
+	return func(key string, call *KeywordCall) (ParsedField, error) {
+		return fn(key, call.Raw)
+	}
+}
+
+func newOptions(opts []Option) *options {
+	options := &options{
+		seed:       time.Now().UnixNano(),
+		keywordSet: make(map[string]KeywordFunc),
+		prefix:     goPrefix,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
@@ -0,0 +1,220 @@
+package goson
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+// parallelQueueSize is a number of records a worker may generate ahead of the writer
+const parallelQueueSize = 16
+
+// parallelRecord is a record generated by a worker
+type parallelRecord struct {
+	worker int
+	record []byte
+	err    error
+}
+
+// parallelWorker generates records with its own source of randomness
+type parallelWorker struct {
+	rnd     *rand.Rand
+	records chan parallelRecord // generated records, used in the ordered mode
+	free    chan []byte         // buffers of written records to reuse
+	turn    chan struct{}       // a turn to generate the next record, used in the ordered mode of stateful templates
+}
+
+func (parallelWorker *parallelWorker) buffer() []byte {
+	select {
+	case buf := <-parallelWorker.free:
+		return buf[:0]
+	default:
+		return nil
+	}
+}
+
+func (parallelWorker *parallelWorker) release(buf []byte) {
+	select {
+	case parallelWorker.free <- buf:
+	default:
+	}
+}
+
+// workerSeed derives a seed of a worker's source from a base seed of a call with splitmix64
+func workerSeed(seed int64, worker int) int64 {
+	z := uint64(seed) + uint64(worker+1)*0x9e3779b97f4a7c15
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+
+	return int64(z ^ z>>31)
+}
+
+// GenerateParallel generates n records with a number of workers and writes them into w.
+// Every worker has its own source of randomness derived from a seed drawn from the processor's source,
+// so later calls continue the processor's stream as Generate does instead of repeating it.
+// By default records are written as soon as they are ready, so their order varies from run to run.
+// WithOrdered makes worker k generate records k, k+workers, k+2*workers... which are written in that order,
+// so the same seed, calls and numbers of workers give the same output. Records of templates with seq, counter
+// or unique fields, or of a clock that steps per record, depend on the ones before them:
+// in the ordered mode workers take turns to generate them one by one in the order of their indexes,
+// so only writing overlaps generation, and in the default mode their values are taken in any order.
+// Like GenerateTo, it stops when ctx is canceled and returns ctx.Err()
+func (processor *processor) GenerateParallel(ctx context.Context, w io.Writer, n, workers int, opts ...StreamOption) error {
+	if n < 0 {
+		return fmt.Errorf("number of records must not be negative: %d", n)
+	}
+
+	if workers < 1 {
+		return fmt.Errorf("number of workers must be positive: %d", workers)
+	}
+
+	options := newStreamOptions(opts)
+	writer, err := newRecordWriter(w, options)
+	if err != nil {
+		return err
+	}
+
+	if err := writer.begin(); err != nil {
+		return err
+	}
+
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	base := processor.rand.Int63()
+	pool := make([]*parallelWorker, workers)
+	for k := range pool {
+		pool[k] = &parallelWorker{
+			rnd:     rand.New(rand.NewSource(workerSeed(base, k))),
+			records: make(chan parallelRecord, parallelQueueSize),
+			free:    make(chan []byte, parallelQueueSize+1),
+		}
+		if options.ordered && processor.keepsState() {
+			pool[k].turn = make(chan struct{}, 1)
+		}
+	}
+	if pool[0].turn != nil {
+		pool[0].turn <- struct{}{}
+	}
+
+	// in the unordered mode workers share a queue of records and a counter of the next record
+	shared := make(chan parallelRecord, parallelQueueSize*workers)
+	var next int64
+
+	var wg sync.WaitGroup
+	for k, worker := range pool {
+		wg.Add(1)
+		go func(k int, worker *parallelWorker) {
+			defer wg.Done()
+			defer close(worker.records)
+
+			out, step, i := shared, 1, 0
+			if options.ordered {
+				out, step, i = worker.records, workers, k
+			}
+
+			for ; ; i += step {
+				if !options.ordered {
+					i = int(atomic.AddInt64(&next, 1) - 1)
+				}
+
+				if i >= n || workCtx.Err() != nil {
+					return
+				}
+
+				if worker.turn != nil {
+					select {
+					case <-worker.turn:
+					case <-workCtx.Done():
+						return
+					}
+				}
+
+				record, err := processor.appendRecord(worker.buffer(), worker.rnd)
+				if worker.turn != nil {
+					pool[(k+1)%workers].turn <- struct{}{}
+				}
+
+				select {
+				case out <- parallelRecord{worker: k, record: record, err: err}:
+				case <-workCtx.Done():
+					return
+				}
+
+				if err != nil {
+					return
+				}
+			}
+		}(k, worker)
+	}
+
+	go func() {
+		wg.Wait()
+		close(shared)
+	}()
+
+	err = processor.collect(ctx, writer, pool, shared, n, options.ordered)
+	cancel()
+	for _, worker := range pool {
+		for range worker.records {
+		}
+	}
+	for range shared {
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return writer.end()
+}
+
+// collect writes records of the workers in the order they come, or in the order of their indexes
+func (processor *processor) collect(
+	ctx context.Context,
+	writer *recordWriter,
+	pool []*parallelWorker,
+	shared <-chan parallelRecord,
+	n int,
+	ordered bool,
+) error {
+	for i := 0; i < n; i++ {
+		source := shared
+		if ordered {
+			source = pool[i%len(pool)].records
+		}
+
+		var generated parallelRecord
+		var ok bool
+		select {
+		case generated, ok = <-source:
+		case <-ctx.Done():
+		}
+
+		if err := ctx.Err(); err != nil {
+			if flushErr := writer.w.Flush(); flushErr != nil {
+				return flushErr
+			}
+
+			return err
+		}
+
+		if !ok {
+			return fmt.Errorf("worker stopped after %d records", i)
+		}
+
+		if generated.err != nil {
+			return generated.err
+		}
+
+		if err := writer.write(generated.record); err != nil {
+			return err
+		}
+		pool[generated.worker].release(generated.record)
+	}
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package goson
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testParallelBody = []byte(`
+	{
+		"id": "_go:uuid:v7",
+		"email": "_go:<5:lower>@test.com",
+		"tags": ["_go:repeat(1, 3)", "_go:<3>"]
+	}
+`)
+
+func Test_processor_GenerateParallel(t *testing.T) {
+	for _, body := range [][]byte{testParallelBody, testStreamBody} {
+		testProcessor, err := New(body, WithSeed(1))
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, testProcessor.GenerateParallel(context.Background(), &buf, 500, 4))
+
+		scanner := bufio.NewScanner(&buf)
+		records := make(map[string]bool)
+		for scanner.Scan() {
+			assert.True(t, json.Valid(scanner.Bytes()))
+			records[scanner.Text()] = true
+		}
+		assert.Len(t, records, 500)
+	}
+}
+
+func Test_processor_GenerateParallel_ordered(t *testing.T) {
+	generate := func(testProcessor Processor, workers int) []byte {
+		var buf bytes.Buffer
+		require.NoError(t, testProcessor.GenerateParallel(
+			context.Background(), &buf, 300, workers, WithOrdered(), WithFormat(FormatJSONArray),
+		))
+
+		return buf.Bytes()
+	}
+
+	first, err := New(testStreamBody, WithSeed(1))
+	require.NoError(t, err)
+	second, err := New(testStreamBody, WithSeed(1))
+	require.NoError(t, err)
+
+	got := generate(first, 3)
+	assert.Equal(t, got, generate(second, 3))
+	assert.Equal(t, generate(first, 3), generate(second, 3))
+	assert.NotEqual(t, got, generate(first, 3))
+
+	other, err := New(testStreamBody, WithSeed(1))
+	require.NoError(t, err)
+	assert.NotEqual(t, got, generate(other, 2))
+
+	var records []map[string]string
+	require.NoError(t, json.Unmarshal(got, &records))
+	require.Len(t, records, 300)
+	for _, record := range records {
+		assert.Equal(t, record["password"], record["confirm_password"])
+	}
+}
+
+func Test_processor_GenerateParallel_orderedStateful(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		stepping bool // a clock that steps per record
+	}{
+		{name: "sequence", body: `{"id": "_go:seq", "n": "_go:<3>"}`},
+		{name: "counter", body: `{"id": "_go:counter(ids)", "items": ["_go:repeat(0, 3)", "_go:counter(ids)"]}`},
+		{name: "unique", body: `{"n": "_go:unique(int(1, 3000))"}`},
+		{name: "advancing clock", body: `{"at": "_go:timestamp(rfc3339)", "n": "_go:<3>"}`, stepping: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			generate := func() []byte {
+				opts := []Option{WithSeed(1)}
+				if tt.stepping {
+					opts = append(opts, WithClock(NewAdvancingClock(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), time.Second)))
+				}
+
+				testProcessor, err := New([]byte(tt.body), opts...)
+				require.NoError(t, err)
+
+				var buf bytes.Buffer
+				require.NoError(t, testProcessor.GenerateParallel(context.Background(), &buf, 2000, 8, WithOrdered()))
+
+				return buf.Bytes()
+			}
+
+			got := generate()
+			for i := 0; i < 5; i++ {
+				assert.Equal(t, got, generate())
+			}
+		})
+	}
+
+	testProcessor, err := New([]byte(`{"id": "_go:seq", "n": "_go:<3>"}`))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, testProcessor.GenerateParallel(context.Background(), &buf, 2000, 8, WithOrdered()))
+
+	scanner := bufio.NewScanner(&buf)
+	for i := 1; scanner.Scan(); i++ {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		require.Equal(t, float64(i), record["id"])
+	}
+}
+
+func Test_processor_GenerateParallel_errors(t *testing.T) {
+	testProcessor, err := New(testStreamBody)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.Error(t, testProcessor.GenerateParallel(context.Background(), &buf, -1, 2))
+	assert.Error(t, testProcessor.GenerateParallel(context.Background(), &buf, 1, 0))
+	assert.Error(t, testProcessor.GenerateParallel(context.Background(), &buf, 1, 2, WithFormat(Format(7))))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = testProcessor.GenerateParallel(ctx, &buf, 1000, 4, WithOrdered())
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	broken, err := New([]byte(`{"nan": "_go:nan"}`), WithKeywords(map[string]NewFieldFunc{
+		"nan": newTestNaNField,
+	}))
+	require.NoError(t, err)
+	assert.Error(t, broken.GenerateParallel(context.Background(), &buf, 100, 4))
+}
+
+type testNaNField struct {
+	name string
+}
+
+func (testNaNField *testNaNField) Name() string {
+	return testNaNField.name
+}
+
+func (testNaNField *testNaNField) Value() interface{} {
+	return math.NaN()
+}
+
+func newTestNaNField(key, _ string) (ParsedField, error) {
+	return &testNaNField{
+		name: key,
+	}, nil
+}
+
+func Test_processor_Generate_concurrent(t *testing.T) {
+	for _, body := range [][]byte{testParallelBody, testStreamBody} {
+		testProcessor, err := New(body)
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					assert.True(t, json.Valid(testProcessor.Generate()))
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
@@ -1,86 +1,628 @@
 package goson
 
 import (
+	"bytes"
 	"encoding/json"
-	"reflect"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
 	"strings"
 )
 
-// iParser parses an input bytes and transforms it into a ParsedFields
+// iParser parses an input bytes and transforms it into a ParsedFields in the order of the input.
+// Link binds the parsed dependent fields to their dependencies
 type iParser interface {
-	Parse([]byte) (map[string]ParsedField, error)
+	Parse([]byte) ([]ParsedField, error)
+	Link([]ParsedField) ([]*dependencyLink, error)
 }
 
 type parser struct {
-	keywordSet map[string]NewFieldFunc
+	keywordSet map[string]KeywordFunc
+	prefix     string                 // marks directives, goPrefix by default
 	rawFields  map[string]interface{} // original fields as key:value map. need it for a referenceFields
+	allErrors  bool                   // collect every error instead of stopping at the first one
+	clock      Clock                  // a clock of time-based fields, nil for the system one
+	counters   *counterSet            // counters of sequence fields, kept by a processor
+	stateful   bool                   // the template has fields that depend on the records before: seq, counter or unique
+
+	path    fieldPath        // path of the field being parsed
+	expr    int              // a number of the ${...} expression being parsed within a string, 0 outside of them
+	offsets map[string]int64 // byte offsets of the template values by their paths
+	errs    []error
+	failed  []fieldPath // paths of the fields that failed to parse in the all-errors mode
 }
 
-func (parser *parser) Parse(body []byte) (map[string]ParsedField, error) {
+func (parser *parser) Parse(body []byte) ([]ParsedField, error) {
 	return parser.parse(body)
 }
 
-func (parser *parser) parse(body []byte) (map[string]ParsedField, error) {
-	parser.rawFields = make(map[string]interface{})
-	if err := json.Unmarshal(body, &parser.rawFields); err != nil {
+func (parser *parser) parse(body []byte) ([]ParsedField, error) {
+	root, offsets, err := decodeRawObject(body)
+	if err != nil {
 		return nil, err
 	}
 
-	fields := make(map[string]ParsedField)
-	for key, value := range parser.rawFields {
-		field, err := parser.parseField(key, value)
-		if err != nil {
-			return nil, err
+	parser.rawFields = root.values
+	parser.offsets = offsets
+	parser.path = nil
+	parser.errs = nil
+	parser.failed = nil
+	parser.stateful = false
+
+	fields, err := parser.parseFields(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(parser.errs) > 0 {
+		// the fields that did parse are linked too, so references are reported along with the parse errors
+		_, linkErrs := parser.link(fields)
+
+		return nil, &MultiError{
+			Errors: append(parser.errs, linkErrs...),
 		}
-		fields[key] = field
 	}
 
 	return fields, nil
 }
 
+// Link checks the references of the parsed fields and orders them for the resolution
+func (parser *parser) Link(fields []ParsedField) ([]*dependencyLink, error) {
+	links, errs := parser.link(fields)
+
+	switch {
+	case len(errs) == 0:
+		return links, nil
+	case parser.allErrors:
+		return nil, &MultiError{
+			Errors: errs,
+		}
+	default:
+		return nil, errs[0]
+	}
+}
+
+// link links the fields and locates the errors in the template.
+// References into the fields that failed to parse are skipped, they are broken by those failures
+func (parser *parser) link(fields []ParsedField) ([]*dependencyLink, []error) {
+	links, errs := linkDependencies(fields)
+
+	located := errs[:0]
+	for _, err := range errs {
+		switch err := err.(type) {
+		case *ReferenceError:
+			if parser.isFailedReference(err.Reference) {
+				continue
+			}
+			err.Offset = parser.offsetOf(err.Path)
+		case *SyntaxError:
+			err.Offset = parser.offsetOf(err.Path)
+		}
+		located = append(located, err)
+	}
+
+	return links, located
+}
+
+// isFailedReference reports whether a reference points into or contains a field that failed to parse
+func (parser *parser) isFailedReference(reference string) bool {
+	target, err := parseFieldPath(reference)
+	if err != nil {
+		return false
+	}
+
+	for _, failed := range parser.failed {
+		if target.overlaps(failed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fail records an error and lets the parsing go on in the all-errors mode, otherwise it just returns the error.
+// A failed field is replaced with a null, so the rest of the template keeps its paths for the linking
+func (parser *parser) fail(name string, err error) (ParsedField, error) {
+	if !parser.allErrors {
+		return nil, err
+	}
+
+	parser.errs = append(parser.errs, err)
+	parser.failed = append(parser.failed, append(fieldPath(nil), parser.path...))
+
+	return newStaticField(name, nil), nil
+}
+
+// location returns a path and an offset of the field being parsed
+func (parser *parser) location() (string, int64) {
+	path := parser.path.String()
+
+	return path, parser.offsetOf(path)
+}
+
+func (parser *parser) offsetOf(path string) int64 {
+	if offset, ok := parser.offsets[path]; ok {
+		return offset
+	}
+
+	return -1
+}
+
+// rawObject is a decoded JSON object that keeps the order of its keys.
+// Keys are stored without their presence suffixes, see splitOptionalKey
+type rawObject struct {
+	keys     []string
+	values   map[string]interface{}
+	presence map[string]presence // presence of optional keys, nil when there are none
+}
+
+func (rawObject *rawObject) setPresence(key string, keyPresence *presence) {
+	switch {
+	case keyPresence != nil && rawObject.presence == nil:
+		rawObject.presence = map[string]presence{key: *keyPresence}
+	case keyPresence != nil:
+		rawObject.presence[key] = *keyPresence
+	default:
+		delete(rawObject.presence, key)
+	}
+}
+
+// rawDecoder decodes a template. Unlike json.Unmarshal into a map, it keeps the order of keys at every level
+// and remembers a byte offset of every value
+type rawDecoder struct {
+	body    []byte
+	decoder *json.Decoder
+	path    fieldPath
+	offsets map[string]int64
+}
+
+func decodeRawObject(body []byte) (*rawObject, map[string]int64, error) {
+	rawDecoder := &rawDecoder{
+		body:    body,
+		decoder: json.NewDecoder(bytes.NewReader(body)),
+		offsets: make(map[string]int64),
+	}
+
+	value, err := rawDecoder.decodeValue()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := rawDecoder.decoder.Token(); err != io.EOF {
+		return nil, nil, rawDecoder.syntaxError(errors.New("unexpected data after the top-level object"))
+	}
+
+	root, ok := value.(*rawObject)
+	if !ok {
+		return nil, nil, &SyntaxError{
+			Err: errors.New("template must be a JSON object"),
+		}
+	}
+
+	return root, rawDecoder.offsets, nil
+}
+
+func (rawDecoder *rawDecoder) decodeValue() (interface{}, error) {
+	rawDecoder.offsets[rawDecoder.path.String()] = rawDecoder.nextOffset()
+	token, err := rawDecoder.decoder.Token()
+	if err != nil {
+		return nil, rawDecoder.syntaxError(err)
+	}
+
+	switch token {
+	case json.Delim('{'):
+		object := &rawObject{
+			values: make(map[string]interface{}),
+		}
+		for rawDecoder.decoder.More() {
+			token, err := rawDecoder.decoder.Token()
+			if err != nil {
+				return nil, rawDecoder.syntaxError(err)
+			}
+
+			key, keyPresence, err := splitOptionalKey(token.(string))
+			if err != nil {
+				return nil, rawDecoder.syntaxError(err)
+			}
+
+			value, err := rawDecoder.decodeChild(pathSegment{key: key, index: keySegment})
+			if err != nil {
+				return nil, err
+			}
+
+			if _, ok := object.values[key]; !ok {
+				object.keys = append(object.keys, key)
+			}
+			object.values[key] = value
+			object.setPresence(key, keyPresence)
+		}
+
+		if _, err := rawDecoder.decoder.Token(); err != nil {
+			return nil, rawDecoder.syntaxError(err)
+		}
+
+		return object, nil
+	case json.Delim('['):
+		array := make([]interface{}, 0)
+		for rawDecoder.decoder.More() {
+			value, err := rawDecoder.decodeChild(pathSegment{index: len(array)})
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+
+		if _, err := rawDecoder.decoder.Token(); err != nil {
+			return nil, rawDecoder.syntaxError(err)
+		}
+
+		return array, nil
+	default:
+		return token, nil
+	}
+}
+
+func (rawDecoder *rawDecoder) decodeChild(segment pathSegment) (interface{}, error) {
+	rawDecoder.path = append(rawDecoder.path, segment)
+	defer func() {
+		rawDecoder.path = rawDecoder.path[:len(rawDecoder.path)-1]
+	}()
+
+	return rawDecoder.decodeValue()
+}
+
+// nextOffset skips the whitespaces and separators that go before the next token
+func (rawDecoder *rawDecoder) nextOffset() int64 {
+	offset := rawDecoder.decoder.InputOffset()
+	for offset < int64(len(rawDecoder.body)) && strings.IndexByte(" \t\r\n,:", rawDecoder.body[offset]) != -1 {
+		offset++
+	}
+
+	return offset
+}
+
+func (rawDecoder *rawDecoder) syntaxError(err error) *SyntaxError {
+	offset := rawDecoder.decoder.InputOffset()
+	var jsonErr *json.SyntaxError
+	switch {
+	case errors.As(err, &jsonErr):
+		offset = jsonErr.Offset
+	case err == io.EOF:
+		err = io.ErrUnexpectedEOF
+	}
+
+	return &SyntaxError{
+		Path:   rawDecoder.path.String(),
+		Offset: offset,
+		Err:    err,
+	}
+}
+
 func (parser *parser) parseField(key string, rawField interface{}) (ParsedField, error) {
-	var field ParsedField
-	var err error
+	return parser.parseValue(pathSegment{key: key, index: keySegment}, key, rawField)
+}
 
-	rawType, rawValue := reflect.TypeOf(rawField), reflect.ValueOf(rawField)
+// parseValue parses a field that lies at the next segment of the current path
+func (parser *parser) parseValue(segment pathSegment, name string, rawField interface{}) (ParsedField, error) {
+	parser.path = append(parser.path, segment)
+	defer func() {
+		parser.path = parser.path[:len(parser.path)-1]
+	}()
 
-	switch rawType.Kind() {
-	case reflect.String:
-		field, err = parser.parseStringField(key, rawValue.String())
+	switch rawValue := rawField.(type) {
+	case string:
+		field, err := parser.parseStringField(name, rawValue)
+		if err != nil {
+			return parser.fail(name, err)
+		}
+
+		return field, nil
+	case *rawObject:
+		return parser.parseObjectField(name, rawValue)
+	case []interface{}:
+		return parser.parseArrayField(name, rawValue)
 	default:
-		field = newStaticField(key, rawField)
+		return newStaticField(name, rawField), nil
+	}
+}
+
+// parseObjectField walks a nested object, so every leaf of it can be a pattern, keyword or reference
+func (parser *parser) parseObjectField(key string, object *rawObject) (ParsedField, error) {
+	fields, err := parser.parseFields(object)
+	if err != nil {
+		return nil, err
+	}
+
+	return newObjectField(key, fields), nil
+}
+
+// parseFields parses fields of an object, the ones with a presence suffix are wrapped into an optionalField
+func (parser *parser) parseFields(object *rawObject) ([]ParsedField, error) {
+	fields := make([]ParsedField, len(object.keys))
+	for i, key := range object.keys {
+		field, err := parser.parseField(key, object.values[key])
+		if err != nil {
+			return nil, err
+		}
+
+		if presence, ok := object.presence[key]; ok && field != nil {
+			field = newOptionalField(field, presence)
+		}
+		fields[i] = field
+	}
+
+	return fields, nil
+}
+
+// parseArrayField walks a nested array. Items are named after their indexes
+func (parser *parser) parseArrayField(key string, rawArray []interface{}) (ParsedField, error) {
+	if len(rawArray) > 0 && parser.isRepeatDirective(rawArray[0]) {
+		field, err := parser.parseRepeatField(key, rawArray)
+		if err != nil {
+			return parser.fail(key, err)
+		}
+
+		return field, nil
+	}
+
+	items := make([]ParsedField, len(rawArray))
+	for i, itemValue := range rawArray {
+		item, err := parser.parseValue(pathSegment{index: i}, strconv.Itoa(i), itemValue)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+
+	return newArrayField(key, items), nil
+}
+
+// repeatKeyword is an array directive: ["_go:repeat(3, 10)", item]
+const repeatKeyword = "repeat"
+
+func (parser *parser) isRepeatDirective(rawField interface{}) bool {
+	value, ok := rawField.(string)
+	if !ok || parser.isStaticString(value) {
+		return false
+	}
+
+	return keywordName(value[len(parser.prefix):]) == repeatKeyword
+}
+
+// parseRepeatField compiles a repeat directive followed by exactly one item template
+func (parser *parser) parseRepeatField(key string, rawArray []interface{}) (ParsedField, error) {
+	call, err := parseKeywordCall(rawArray[0].(string)[len(parser.prefix):])
+	if err != nil {
+		return nil, parser.syntaxError(err)
+	}
+
+	if len(rawArray) != 2 {
+		return nil, parser.keywordError(repeatKeyword, fmt.Errorf(
+			"keyword %s expects exactly one item template, got %d", repeatKeyword, len(rawArray)-1,
+		))
+	}
+
+	item, err := parser.parseValue(pathSegment{index: 1}, key, rawArray[1])
+	if err != nil || item == nil {
+		return nil, err
 	}
 
-	return field, err
+	field, err := newRepeatField(key, call, item)
+	if err != nil {
+		return nil, parser.keywordError(repeatKeyword, err)
+	}
+
+	return field, nil
 }
 
+// goPrefix is a default prefix of directives, it is matched only at the start of a string
 const goPrefix = "_go:"
 
+// prefixEscape before the prefix makes a string static: "\\_go:docs" gives "_go:docs"
+const prefixEscape = '\\'
+
+// referencePrefix marks a directive that points to another field, e.g. "_go:_password"
+const referencePrefix = "_"
+
+// keywordArgSeparator separates a keyword from its arguments in a short form, e.g. "_go:uuid:v7"
+const keywordArgSeparator = ":"
+
 func (parser *parser) parseStringField(key, value string) (ParsedField, error) {
+	if parser.isEscapedPrefix(value) {
+		return newStaticField(key, value[1:]), nil
+	}
+
 	if parser.isStaticString(value) {
 		return newStaticField(key, value), nil
 	}
 
-	value = value[len(goPrefix):]
+	value = value[len(parser.prefix):]
+	if keywordName(value) == uniqueKeyword {
+		return parser.parseUniqueField(key, value)
+	}
+
+	if hasInterpolation(value) {
+		return parser.parseTemplateField(key, value)
+	}
+
 	if parser.isValidKeywordString(value) {
-		fn := parser.keywordSet[value]
-		return fn(key, value)
+		return parser.parseKeywordField(key, value)
 	}
 
 	if parser.isValidReferenceString(value) {
-		return newReferenceField(key, value[1:]), nil
+		return newReferenceField(key, value[len(referencePrefix):]), nil
+	}
+
+	switch {
+	case strings.ContainsRune(value, '<'):
+		field, err := newPatternField(key, value)
+		if err != nil {
+			return nil, parser.patternError(err)
+		}
+
+		return field, nil
+	case strings.HasPrefix(value, referencePrefix):
+		return nil, parser.referenceError(value[len(referencePrefix):])
+	default:
+		path, offset := parser.location()
+		return nil, &UnknownKeywordError{
+			Path:    path,
+			Offset:  offset,
+			Keyword: keywordName(value),
+		}
+	}
+}
+
+func (parser *parser) parseKeywordField(key, value string) (ParsedField, error) {
+	call, err := parseKeywordCall(value)
+	if err != nil {
+		return nil, parser.syntaxError(err)
+	}
+
+	fn := parser.keywordSet[call.Keyword]
+	field, err := fn(key, call)
+	if err != nil {
+		return nil, parser.keywordError(call.Keyword, err)
+	}
+
+	if field, ok := field.(timedField); ok && parser.clock != nil {
+		field.useClock(parser.clock)
+	}
+
+	if field, ok := field.(countedField); ok && parser.counters != nil {
+		if err := field.useCounters(parser.counters, parser.counterName()); err != nil {
+			return nil, parser.keywordError(call.Keyword, err)
+		}
+		parser.stateful = true
+	}
+
+	return field, parser.checkDependencies(field)
+}
+
+// counterName names a sequence after the path of its field,
+// a sequence of a ${...} expression gets its number within the string: "label#2"
+func (parser *parser) counterName() string {
+	if parser.expr == 0 {
+		return parser.path.String()
+	}
+
+	return parser.path.String() + "#" + strconv.Itoa(parser.expr)
+}
+
+// parseTemplateField compiles a string with ${...} expressions into a single field:
+// "${_first}.${_last}@<4>.com". Text between expressions may contain pattern blocks
+func (parser *parser) parseTemplateField(key, value string) (ParsedField, error) {
+	tokens, err := splitTemplate(value)
+	if err != nil {
+		return nil, parser.syntaxError(err)
+	}
+
+	pieces := make([]ParsedField, 0, len(tokens))
+	defer func() {
+		parser.expr = 0
+	}()
+	for _, token := range tokens {
+		if token.expr {
+			parser.expr++
+		}
+
+		piece, err := parser.parseTemplateToken(key, token)
+		if err != nil {
+			return nil, err
+		}
+		pieces = append(pieces, piece)
+	}
+
+	field := newTemplateField(key, pieces)
+
+	return field, parser.checkDependencies(field)
+}
+
+func (parser *parser) parseTemplateToken(key string, token templateToken) (ParsedField, error) {
+	switch {
+	case !token.expr && hasUnescaped(token.text, '<'):
+		field, err := newPatternField(key, token.text)
+		if err != nil {
+			return nil, parser.patternError(err)
+		}
+
+		return field, nil
+	case !token.expr:
+		return newStaticField(key, unescapeString(token.text)), nil
+	case strings.HasPrefix(token.text, referencePrefix):
+		return newReferenceField(key, token.text[len(referencePrefix):]), nil
+	case parser.isValidKeywordString(token.text):
+		return parser.parseKeywordField(key, token.text)
+	default:
+		path, offset := parser.location()
+		return nil, &UnknownKeywordError{
+			Path:    path,
+			Offset:  offset,
+			Keyword: keywordName(token.text),
+		}
 	}
+}
+
+func (parser *parser) syntaxError(err error) error {
+	path, offset := parser.location()
 
-	return newPatternField(key, value)
+	return &SyntaxError{
+		Path:   path,
+		Offset: offset,
+		Err:    err,
+	}
+}
+
+func (parser *parser) keywordError(keyword string, err error) error {
+	path, offset := parser.location()
+
+	return &KeywordError{
+		Path:    path,
+		Offset:  offset,
+		Keyword: keyword,
+		Err:     err,
+	}
+}
+
+func (parser *parser) referenceError(reference string) error {
+	path, offset := parser.location()
+
+	return &ReferenceError{
+		Path:      path,
+		Offset:    offset,
+		Reference: reference,
+		Err:       fmt.Errorf("unknown reference: %s", reference),
+	}
+}
+
+func (parser *parser) patternError(err error) error {
+	var patternErr *PatternError
+	if !errors.As(err, &patternErr) {
+		return err
+	}
+
+	patternErr.Path, patternErr.Offset = parser.location()
+
+	return patternErr
 }
 
 func (parser *parser) isStaticString(value string) bool {
-	return !strings.Contains(value, goPrefix)
+	return !strings.HasPrefix(value, parser.prefix)
+}
+
+// isEscapedPrefix reports whether a string starts with an escaped prefix: "\\_go:" or "\\\\_go:".
+// One backslash is dropped, so a template can contain the prefix as data
+func (parser *parser) isEscapedPrefix(value string) bool {
+	trimmed := strings.TrimLeft(value, string(prefixEscape))
+
+	return len(trimmed) < len(value) && strings.HasPrefix(trimmed, parser.prefix)
 }
 
 func (parser *parser) isValidKeywordString(value string) bool {
+	name := keywordName(value)
 	for fieldKey := range parser.keywordSet {
-		if value == fieldKey {
+		if name == fieldKey {
 			return true
 		}
 	}
@@ -88,35 +630,72 @@ func (parser *parser) isValidKeywordString(value string) bool {
 	return false
 }
 
+// isValidReferenceString checks that a reference starts with an existing top-level field.
+// The rest of the path is checked once the whole template is parsed
 func (parser *parser) isValidReferenceString(value string) bool {
-	if _, ok := parser.rawFields[value[1:]]; ok {
-		return true
+	if !strings.HasPrefix(value, referencePrefix) {
+		return false
 	}
 
-	return false
+	return parser.isKnownRoot(value[len(referencePrefix):])
 }
 
-func newParser() iParser {
-	defaultKeywordSet := getDefaultKeywordSet()
-	return &parser{
-		keywordSet: defaultKeywordSet,
+// checkDependencies makes sure that fields computed from other fields point to the existing ones
+func (parser *parser) checkDependencies(field ParsedField) error {
+	dependent, ok := field.(dependentField)
+	if !ok {
+		return nil
 	}
+
+	for _, dependency := range dependent.dependencies() {
+		if !parser.isKnownRoot(dependency) {
+			return parser.referenceError(dependency)
+		}
+	}
+
+	return nil
 }
 
-func getDefaultKeywordSet() map[string]NewFieldFunc {
-	return map[string]NewFieldFunc{
+func (parser *parser) isKnownRoot(rawPath string) bool {
+	path, err := parseFieldPath(rawPath)
+	if err != nil {
+		return false
+	}
+
+	_, ok := parser.rawFields[path[0].key]
+
+	return ok
+}
+
+func getDefaultKeywordSet() map[string]KeywordFunc {
+	return map[string]KeywordFunc{
 		"timestamp": newTimestampField,
 		"uuid":      newUUIDField,
+		"int":       newIntField,
+		"float":     newFloatField,
+		"decimal":   newDecimalField,
+		"regex":     newRegexField,
+		"oneof":     newOneOfField,
+		"seq":       newSequenceField,
+		"counter":   newCounterField,
 	}
 }
 
-func newParserWithCustomKeywords(fields map[string]NewFieldFunc) iParser {
+func newParserWithCustomKeywords(fields map[string]KeywordFunc) iParser {
+	return newParser(&options{keywordSet: fields, prefix: goPrefix}, newCounterSet())
+}
+
+func newParser(options *options, counters *counterSet) *parser {
 	customKeywordSet := getDefaultKeywordSet()
-	for key, fn := range fields {
+	for key, fn := range options.keywordSet {
 		customKeywordSet[key] = fn
 	}
 
 	return &parser{
 		keywordSet: customKeywordSet,
+		prefix:     options.prefix,
+		allErrors:  options.allErrors,
+		clock:      options.clock,
+		counters:   counters,
 	}
 }
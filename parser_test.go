@@ -2,6 +2,7 @@ package goson
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -10,6 +11,7 @@ import (
 func getTestParserStruct() parser {
 	return parser{
 		keywordSet: getDefaultKeywordSet(),
+		prefix:     goPrefix,
 		rawFields: map[string]interface{}{
 			"email":            "_go:<4/1>@test.com",
 			"password":         "_go:<5/1/1>",
@@ -31,6 +33,11 @@ func Test_parser_isStaticString(t *testing.T) {
 			input: "some string",
 			want:  true,
 		},
+		{
+			name:  "positive prefix in the middle",
+			input: "see _go:docs",
+			want:  true,
+		},
 		{
 			name:  "negative",
 			input: "_go:some string",
@@ -46,6 +53,49 @@ func Test_parser_isStaticString(t *testing.T) {
 	}
 }
 
+func Test_parser_parseStringField_prefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		input  string
+		want   interface{}
+	}{
+		{
+			name:   "prefix in the middle",
+			prefix: goPrefix,
+			input:  "see _go:docs",
+			want:   "see _go:docs",
+		},
+		{
+			name:   "escaped prefix",
+			prefix: goPrefix,
+			input:  "\\_go:<5>",
+			want:   "_go:<5>",
+		},
+		{
+			name:   "escaped backslash",
+			prefix: goPrefix,
+			input:  "\\\\_go:<5>",
+			want:   "\\_go:<5>",
+		},
+		{
+			name:   "custom prefix keeps the default one",
+			prefix: "$gen:",
+			input:  "_go:<5>",
+			want:   "_go:<5>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testParser := getTestParserStruct()
+			testParser.prefix = tt.prefix
+			got, err := testParser.parseStringField("key", tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Value())
+		})
+	}
+}
+
 func Test_parser_isValidKeywordString(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -62,6 +112,11 @@ func Test_parser_isValidKeywordString(t *testing.T) {
 			input: "uuid",
 			want:  true,
 		},
+		{
+			name:  "positive with arguments",
+			input: "uuid:v7",
+			want:  true,
+		},
 		{
 			name:  "negative #1",
 			input: "city",
@@ -133,10 +188,12 @@ func Test_parser_parseStringField(t *testing.T) {
 				name: "email",
 				pattern: &generator{
 					pattern: strPattern{
-						suffix:  "@test.com",
-						letters: 4,
-						digits:  1,
-						length:  5,
+						suffix: "@test.com",
+						classes: []charClass{
+							{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+							{pool: []rune(bytePoolDigits), count: charRange{1, 1}},
+						},
+						length: 5,
 					},
 				},
 			},
@@ -148,6 +205,7 @@ func Test_parser_parseStringField(t *testing.T) {
 			},
 			want: &timestampField{
 				name: "time",
+				unit: time.Second,
 			},
 		},
 		{
@@ -160,6 +218,45 @@ func Test_parser_parseStringField(t *testing.T) {
 				referenceTo: "password",
 			},
 		},
+		{
+			name: "positive timestamp with unit",
+			args: args{
+				"time", "_go:timestamp(ms)",
+			},
+			want: &timestampField{
+				name: "time",
+				unit: time.Millisecond,
+			},
+		},
+		{
+			name: "positive uuid #1",
+			args: args{
+				"id", "_go:uuid",
+			},
+			want: &uuidField{
+				name: "id",
+			},
+		},
+		{
+			name: "positive uuid #2",
+			args: args{
+				"id", "_go:uuid:v7",
+			},
+			want: &uuidV7Field{
+				name: "id",
+			},
+		},
+		{
+			name: "positive uuid #3",
+			args: args{
+				"id", "_go:uuid(v5, url, _email)",
+			},
+			want: &uuidV5Field{
+				name:      "id",
+				namespace: uuidNamespaceURL,
+				from:      "email",
+			},
+		},
 		{
 			name: "invalid reference",
 			args: args{
@@ -174,6 +271,48 @@ func Test_parser_parseStringField(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "invalid timestamp unit",
+			args: args{
+				"time", "_go:timestamp(h)",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid timestamp arity",
+			args: args{
+				"time", "_go:timestamp(s, ms)",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid keyword arguments",
+			args: args{
+				"time", "_go:timestamp(s",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid uuid version",
+			args: args{
+				"id", "_go:uuid:v3",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid uuid namespace",
+			args: args{
+				"id", "_go:uuid:v5:isbn:_email",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid uuid reference",
+			args: args{
+				"id", "_go:uuid:v5:_name",
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid pattern #1",
 			args: args{
@@ -231,10 +370,12 @@ func Test_parser_parseField(t *testing.T) {
 				name: "email",
 				pattern: &generator{
 					pattern: strPattern{
-						suffix:  "@test.com",
-						letters: 4,
-						digits:  1,
-						length:  5,
+						suffix: "@test.com",
+						classes: []charClass{
+							{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+							{pool: []rune(bytePoolDigits), count: charRange{1, 1}},
+						},
+						length: 5,
 					},
 				},
 			},
@@ -246,6 +387,7 @@ func Test_parser_parseField(t *testing.T) {
 			},
 			want: &timestampField{
 				name: "time",
+				unit: time.Second,
 			},
 		},
 		{
@@ -258,6 +400,86 @@ func Test_parser_parseField(t *testing.T) {
 				referenceTo: "password",
 			},
 		},
+		{
+			name: "positive nested object",
+			args: args{
+				"user", &rawObject{
+					keys: []string{"email", "confirm", "age"},
+					values: map[string]interface{}{
+						"email":   "_go:<4/1>@test.com",
+						"confirm": "_go:_password",
+						"age":     float64(18),
+					},
+				},
+			},
+			want: &objectField{
+				name: "user",
+				fields: []ParsedField{
+					&patternField{
+						name: "email",
+						pattern: &generator{
+							pattern: strPattern{
+								suffix: "@test.com",
+								classes: []charClass{
+									{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+									{pool: []rune(bytePoolDigits), count: charRange{1, 1}},
+								},
+								length: 5,
+							},
+						},
+					},
+					&referenceField{
+						name:        "confirm",
+						referenceTo: "password",
+					},
+					&staticField{
+						name:  "age",
+						value: float64(18),
+					},
+				},
+			},
+		},
+		{
+			name: "positive nested array",
+			args: args{
+				"tags", []interface{}{
+					"_go:<3>",
+					&rawObject{
+						keys:   []string{"time"},
+						values: map[string]interface{}{"time": "_go:timestamp"},
+					},
+					nil,
+				},
+			},
+			want: &arrayField{
+				name: "tags",
+				items: []ParsedField{
+					&patternField{
+						name: "0",
+						pattern: &generator{
+							pattern: strPattern{
+								classes: []charClass{
+									{pool: []rune(bytePoolLetters), count: charRange{3, 3}},
+								},
+								length: 3,
+							},
+						},
+					},
+					&objectField{
+						name: "1",
+						fields: []ParsedField{
+							&timestampField{
+								name: "time",
+								unit: time.Second,
+							},
+						},
+					},
+					&staticField{
+						name: "2",
+					},
+				},
+			},
+		},
 		{
 			name: "invalid reference",
 			args: args{
@@ -293,6 +515,62 @@ func Test_parser_parseField(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "positive repeat",
+			args: args{
+				"tags", []interface{}{
+					"_go:repeat(3, 10)",
+					"_go:<3>",
+				},
+			},
+			want: &repeatField{
+				name: "tags",
+				min:  3,
+				max:  10,
+				item: &patternField{
+					name: "tags",
+					pattern: &generator{
+						pattern: strPattern{
+							classes: []charClass{
+								{pool: []rune(bytePoolLetters), count: charRange{3, 3}},
+							},
+							length: 3,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid repeat without template",
+			args: args{
+				"tags", []interface{}{"_go:repeat(3)"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid repeat with several templates",
+			args: args{
+				"tags", []interface{}{"_go:repeat(3)", "_go:<3>", "_go:<4>"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid repeat bounds",
+			args: args{
+				"tags", []interface{}{"_go:repeat(3, 1)", "_go:<3>"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid nested pattern",
+			args: args{
+				"user", &rawObject{
+					keys:   []string{"emails"},
+					values: map[string]interface{}{"emails": []interface{}{"_go:<5/1/c>"}},
+				},
+			},
+			wantErr: true,
+		},
 	}
 
 	testParser := getTestParserStruct()
@@ -313,7 +591,7 @@ func Test_parser_parse(t *testing.T) {
 	tests := []struct {
 		name    string
 		input   []byte
-		want    map[string]ParsedField
+		want    []ParsedField
 		wantErr bool
 	}{
 		{
@@ -326,40 +604,46 @@ func Test_parser_parse(t *testing.T) {
 					"escaped_field": "_go:\\<0\\> <5>"
 				}
 			`),
-			want: map[string]ParsedField{
-				"confirm_password": &referenceField{
-					name:        "confirm_password",
-					referenceTo: "password",
-				},
-				"email": &patternField{
+			want: []ParsedField{
+				&patternField{
 					name: "email",
 					pattern: &generator{
 						strPattern{
-							suffix:  "@test.com",
-							letters: 4,
-							digits:  1,
-							length:  5,
+							suffix: "@test.com",
+							classes: []charClass{
+								{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+								{pool: []rune(bytePoolDigits), count: charRange{1, 1}},
+							},
+							length: 5,
 						},
 					},
 				},
-				"escaped_field": &patternField{
-					name: "escaped_field",
+				&patternField{
+					name: "password",
 					pattern: &generator{
 						strPattern{
-							prefix:  "<0> ",
-							letters: 5,
-							length:  5,
+							classes: []charClass{
+								{pool: []rune(bytePoolLetters), count: charRange{5, 5}},
+								{pool: []rune(bytePoolDigits), count: charRange{2, 2}},
+								{pool: []rune(bytePoolSpecials), count: charRange{1, 1}},
+							},
+							length: 8,
 						},
 					},
 				},
-				"password": &patternField{
-					name: "password",
+				&referenceField{
+					name:        "confirm_password",
+					referenceTo: "password",
+				},
+				&patternField{
+					name: "escaped_field",
 					pattern: &generator{
 						strPattern{
-							letters:  5,
-							digits:   2,
-							specials: 1,
-							length:   8,
+							prefix: "<0> ",
+							classes: []charClass{
+								{pool: []rune(bytePoolLetters), count: charRange{5, 5}},
+							},
+							length: 5,
 						},
 					},
 				},
@@ -432,7 +716,7 @@ func Test_parser_Parse(t *testing.T) {
 	tests := []struct {
 		name    string
 		input   []byte
-		want    map[string]ParsedField
+		want    []ParsedField
 		wantErr bool
 	}{
 		{
@@ -445,40 +729,46 @@ func Test_parser_Parse(t *testing.T) {
 					"escaped_field": "_go:\\<0\\> <5>"
 				}
 			`),
-			want: map[string]ParsedField{
-				"confirm_password": &referenceField{
-					name:        "confirm_password",
-					referenceTo: "password",
-				},
-				"email": &patternField{
+			want: []ParsedField{
+				&patternField{
 					name: "email",
 					pattern: &generator{
 						strPattern{
-							suffix:  "@test.com",
-							letters: 4,
-							digits:  1,
-							length:  5,
+							suffix: "@test.com",
+							classes: []charClass{
+								{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+								{pool: []rune(bytePoolDigits), count: charRange{1, 1}},
+							},
+							length: 5,
 						},
 					},
 				},
-				"escaped_field": &patternField{
-					name: "escaped_field",
+				&patternField{
+					name: "password",
 					pattern: &generator{
 						strPattern{
-							prefix:  "<0> ",
-							letters: 5,
-							length:  5,
+							classes: []charClass{
+								{pool: []rune(bytePoolLetters), count: charRange{5, 5}},
+								{pool: []rune(bytePoolDigits), count: charRange{2, 2}},
+								{pool: []rune(bytePoolSpecials), count: charRange{1, 1}},
+							},
+							length: 8,
 						},
 					},
 				},
-				"password": &patternField{
-					name: "password",
+				&referenceField{
+					name:        "confirm_password",
+					referenceTo: "password",
+				},
+				&patternField{
+					name: "escaped_field",
 					pattern: &generator{
 						strPattern{
-							letters:  5,
-							digits:   2,
-							specials: 1,
-							length:   8,
+							prefix: "<0> ",
+							classes: []charClass{
+								{pool: []rune(bytePoolLetters), count: charRange{5, 5}},
+							},
+							length: 5,
 						},
 					},
 				},
@@ -546,3 +836,64 @@ func Test_parser_Parse(t *testing.T) {
 		})
 	}
 }
+
+func Test_decodeRawObject(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    *rawObject
+		wantErr bool
+	}{
+		{
+			name:  "positive keeps order",
+			input: `{"b": 1, "a": {"d": [true, null], "c": "x"}}`,
+			want: &rawObject{
+				keys: []string{"b", "a"},
+				values: map[string]interface{}{
+					"b": float64(1),
+					"a": &rawObject{
+						keys: []string{"d", "c"},
+						values: map[string]interface{}{
+							"d": []interface{}{true, nil},
+							"c": "x",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:  "positive duplicated key",
+			input: `{"a": 1, "b": 2, "a": 3}`,
+			want: &rawObject{
+				keys:   []string{"a", "b"},
+				values: map[string]interface{}{"a": float64(3), "b": float64(2)},
+			},
+		},
+		{
+			name:    "not an object",
+			input:   `["a"]`,
+			wantErr: true,
+		},
+		{
+			name:    "trailing data",
+			input:   `{"a": 1} {"b": 2}`,
+			wantErr: true,
+		},
+		{
+			name:    "trailing comma",
+			input:   `{"a": 1,}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := decodeRawObject([]byte(tt.input))
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
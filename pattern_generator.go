@@ -3,9 +3,11 @@ package goson
 import (
 	"errors"
 	"fmt"
+	"math"
 	"math/rand"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // iPatternGenerator generates a string according to a pattern: <0\0\0>
@@ -14,21 +16,47 @@ import (
 // and the third is a number of special characters.
 // It allowed to discard unused number from the end,
 // so <0\0> and <0> are valid too.
+// Every number can be a range: <3-8/0-2/1> generates from 3 to 8 letters,
+// up to 2 digits and exactly 1 special character, the counts are picked on every call.
+// A number can be followed by a character class: <8:hex>, <2-4:upper/3:digit>,
+// either a named one (see builtinClasses and RegisterPool) or a custom set: <6:[A-F0-9]>.
 // Also, it supports prefix and suffix.
+// A pattern may contain several blocks with literal text between them: "<2:upper>-<4:digit>".
+// Blocks keep their order, characters are shuffled only within a block.
 // For example pattern "<5\1>@test.com"
 // will generate a string of length 6,
 // that contains exact 5 letter and exact 1 digit
 // and after that it will append suffix "@test.com".
 type iPatternGenerator interface {
-	Generate() string
+	Generate(rnd *rand.Rand) string
+	// Append appends a generated value to dst, it doesn't allocate as long as dst has enough capacity
+	Append(dst []byte, rnd *rand.Rand) []byte
 }
 
 type strPattern struct {
 	prefix, suffix string
-	letters        int
-	digits         int
-	specials       int
-	length         int
+	classes        []charClass
+	length         int // max length of a generated value
+}
+
+// charClass is a number of characters to take from a pool
+type charClass struct {
+	pool  []rune
+	count charRange
+}
+
+// charRange is an inclusive range of a number of characters
+type charRange struct {
+	min, max int
+}
+
+// pick returns a random number within the range. Exact counts don't touch the source
+func (charRange charRange) pick(rnd *rand.Rand) int {
+	if charRange.max <= charRange.min {
+		return charRange.min
+	}
+
+	return charRange.min + rnd.Intn(charRange.max-charRange.min+1)
 }
 
 type generator struct {
@@ -36,13 +64,15 @@ type generator struct {
 }
 
 // Generate concatenates prefix, generated value and suffix
-func (generator generator) Generate() string {
-	return fmt.Sprintf(
-		"%s%s%s",
-		generator.pattern.prefix,
-		generator.generate(),
-		generator.pattern.suffix,
-	)
+func (generator generator) Generate(rnd *rand.Rand) string {
+	return string(generator.Append(nil, rnd))
+}
+
+func (generator generator) Append(dst []byte, rnd *rand.Rand) []byte {
+	dst = append(dst, generator.pattern.prefix...)
+	dst = generator.appendValue(dst, rnd)
+
+	return append(dst, generator.pattern.suffix...)
 }
 
 // Character pool: 52 letters, 10 digits and 19 specials.
@@ -50,56 +80,179 @@ const bytePoolLetters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 const bytePoolDigits = "0123456789"
 const bytePoolSpecials = "~!@#$%^&*()_+-={}[]"
 
-// generate takes a random character from a specific byte pool and insert it into a slice of bytes.
-// At the end shuffling a slice of bytes.
-func (generator generator) generate() string {
-	n := generator.pattern.length
-	bytes := make([]byte, n)
+// generate takes a random character from a specific pool and insert it into a slice of runes.
+// At the end shuffling a slice of runes.
+func (generator generator) generate(rnd *rand.Rand) string {
+	return string(generator.appendValue(nil, rnd))
+}
 
-	var i int
-	for j := 0; j < generator.pattern.letters; j++ {
-		char := getRandomCharFromSource(bytePoolLetters)
-		bytes[i] = char
-		i++
+// patternMaxClasses is a number of classes whose counts are kept on the stack
+const patternMaxClasses = 8
+
+func (generator generator) appendValue(dst []byte, rnd *rand.Rand) []byte {
+	var countsBuf [patternMaxClasses]int
+	counts := countsBuf[:0]
+	var n int
+	for _, class := range generator.pattern.classes {
+		count := class.count.pick(rnd)
+		counts = append(counts, count)
+		n += count
 	}
 
-	for j := 0; j < generator.pattern.digits; j++ {
-		char := getRandomCharFromSource(bytePoolDigits)
-		bytes[i] = char
-		i++
+	var runesBuf [patternMaxLen]rune
+	runes := runesBuf[:0]
+	if n > len(runesBuf) {
+		runes = make([]rune, 0, n)
 	}
 
-	for j := 0; j < generator.pattern.specials; j++ {
-		char := getRandomCharFromSource(bytePoolSpecials)
-		bytes[i] = char
-		i++
+	for k, class := range generator.pattern.classes {
+		for j := 0; j < counts[k]; j++ {
+			char := getRandomCharFromSource(rnd, class.pool)
+			runes = append(runes, char)
+		}
 	}
 
-	rand.Shuffle(n, func(i, j int) {
-		bytes[i], bytes[j] = bytes[j], bytes[i]
+	rnd.Shuffle(n, func(i, j int) {
+		runes[i], runes[j] = runes[j], runes[i]
 	})
 
-	return string(bytes)
+	for _, char := range runes {
+		dst = utf8.AppendRune(dst, char)
+	}
+
+	return dst
+}
+
+// capacity is a number of distinct values of the generator.
+// It is exact when pools of the classes don't share characters and it is an upper bound otherwise
+func (generator generator) capacity() float64 {
+	// ways[n] is a number of ways to fill n positions with the classes seen so far divided by n!,
+	// so the arrangements of the classes are counted as multinomial coefficients
+	ways := []float64{1}
+	for _, class := range generator.pattern.classes {
+		next := make([]float64, len(ways)+class.count.max)
+		for n, w := range ways {
+			if w == 0 {
+				continue
+			}
+
+			for c := class.count.min; c <= class.count.max; c++ {
+				next[n+c] += w * math.Pow(float64(len(class.pool)), float64(c)) / factorial(c)
+			}
+		}
+		ways = next
+	}
+
+	var total float64
+	for n, w := range ways {
+		total += w * factorial(n)
+	}
+
+	return total
+}
+
+func factorial(n int) float64 {
+	result := 1.0
+	for i := 2; i <= n; i++ {
+		result *= float64(i)
+	}
+
+	return result
 }
 
-func getRandomCharFromSource(source string) byte {
+func getRandomCharFromSource(rnd *rand.Rand, source []rune) rune {
 	n := len(source)
-	char := source[rand.Intn(n)]
+	char := source[rnd.Intn(n)]
 
 	return char
 }
 
+// segmentedGenerator concatenates several pattern blocks in their order
+type segmentedGenerator struct {
+	segments []generator
+}
+
+func (segmentedGenerator *segmentedGenerator) Generate(rnd *rand.Rand) string {
+	return string(segmentedGenerator.Append(nil, rnd))
+}
+
+func (segmentedGenerator *segmentedGenerator) Append(dst []byte, rnd *rand.Rand) []byte {
+	for _, segment := range segmentedGenerator.segments {
+		dst = segment.Append(dst, rnd)
+	}
+
+	return dst
+}
+
+// capacity is a product of capacities of the segments
+func (segmentedGenerator *segmentedGenerator) capacity() float64 {
+	total := 1.0
+	for _, segment := range segmentedGenerator.segments {
+		total *= segment.capacity()
+	}
+
+	return total
+}
+
 func newPatternGenerator(raw string) (iPatternGenerator, error) {
-	pattern, err := buildPattern(raw)
-	if err != nil {
-		return nil, throwInvalidPatternError(raw, err)
+	chunks := splitPatternBlocks(raw)
+	segments := make([]generator, len(chunks))
+	for i, chunk := range chunks {
+		pattern, err := buildPattern(chunk)
+		if err != nil {
+			return nil, throwInvalidPatternError(raw, err)
+		}
+		segments[i].pattern = pattern
 	}
 
-	return &generator{
-		pattern: pattern,
+	if len(segments) == 1 {
+		return &segments[0], nil
+	}
+
+	return &segmentedGenerator{
+		segments: segments,
 	}, nil
 }
 
+// splitPatternBlocks splits a raw pattern into chunks with a single block each,
+// so "<2:upper>-<4:digit>!" gives "<2:upper>" and "-<4:digit>!".
+// Text after the last block goes to the last chunk.
+func splitPatternBlocks(raw string) []string {
+	var chunks []string
+	var start int
+	for i := 0; i < len(raw); i++ {
+		switch {
+		case raw[i] == '\\':
+			i++
+		case raw[i] == '>':
+			chunks = append(chunks, raw[start:i+1])
+			start = i + 1
+		}
+	}
+
+	tail := raw[start:]
+	if len(chunks) == 0 || hasUnescaped(tail, '<') {
+		return append(chunks, tail)
+	}
+
+	chunks[len(chunks)-1] += tail
+
+	return chunks
+}
+
+func hasUnescaped(raw string, char byte) bool {
+	for i := 0; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+		case char:
+			return true
+		}
+	}
+
+	return false
+}
+
 func buildPattern(raw string) (strPattern, error) {
 	var pattern strPattern
 
@@ -120,25 +273,33 @@ func buildPattern(raw string) (strPattern, error) {
 	return pattern, nil
 }
 
+// unescapeString drops escaping backslashes: \< gives < and \\ gives a single backslash
 func unescapeString(raw string) string {
-	res := make([]byte, 0)
-	for i := 0; i < len(raw); i++ {
-		if i < len(raw) && raw[i] == '\\' && raw[i+1] != '\\' {
+	var builder strings.Builder
+	var escaped bool
+	for _, char := range raw {
+		if char == '\\' && !escaped {
+			escaped = true
 			continue
 		}
 
-		res = append(res, raw[i])
+		escaped = false
+		builder.WriteRune(char)
 	}
 
-	return string(res)
+	return builder.String()
 }
 
+// locatePattern returns byte offsets of the only unescaped "<" and ">" of a raw pattern, or -1
 func locatePattern(raw string) (int, int) {
 	start, end := -1, -1
+	var escaped bool
 	for i, char := range raw {
 		switch {
-		case i > 0 && raw[i-1] == '\\':
-			continue
+		case escaped:
+			escaped = false
+		case char == '\\':
+			escaped = true
 		case char == '<' && start == -1:
 			start = i
 		case char == '<' && start != -1:
@@ -153,38 +314,21 @@ func locatePattern(raw string) (int, int) {
 	return start, end
 }
 
-const (
-	patternLegendLetter = iota
-	patternLegendDigit
-	patternLegendSpecial
-)
-
 const patternSeparator = "/"
+const patternRangeSeparator = "-"
+const patternClassSeparator = ":"
 const patternMaxLen = 64
 
 func parsePattern(body string) (strPattern, error) {
 	var pattern strPattern
-	exploded := strings.Split(body, patternSeparator)
-	if len(exploded) > 3 {
-		return pattern, errors.New("can't split pattern correctly")
-	}
-
+	exploded := splitPattern(body)
 	for i, str := range exploded {
-		num, err := strconv.Atoi(str)
+		class, err := parsePatternPart(i, str)
 		if err != nil {
 			return pattern, err
 		}
-		switch i {
-		case patternLegendLetter:
-			pattern.letters = num
-			pattern.length += num
-		case patternLegendDigit:
-			pattern.digits = num
-			pattern.length += num
-		case patternLegendSpecial:
-			pattern.specials = num
-			pattern.length += num
-		}
+		pattern.classes = append(pattern.classes, class)
+		pattern.length += class.count.max
 	}
 
 	if pattern.length > patternMaxLen {
@@ -199,3 +343,84 @@ func parsePattern(body string) (strPattern, error) {
 
 	return pattern, nil
 }
+
+// parsePatternPart parses a count with an optional class: "5", "2-4:upper", "6:[A-F0-9]".
+// A part without a class takes letters, digits or specials according to its position.
+func parsePatternPart(i int, str string) (charClass, error) {
+	var class charClass
+	rawCount, rawClass := str, ""
+	if j := strings.Index(str, patternClassSeparator); j != -1 {
+		rawCount, rawClass = str[:j], str[j+len(patternClassSeparator):]
+	}
+
+	count, err := parseCharRange(rawCount)
+	if err != nil {
+		return class, err
+	}
+
+	if rawClass == "" {
+		if i >= len(positionalClasses) {
+			return class, errors.New("can't split pattern correctly")
+		}
+		rawClass = positionalClasses[i]
+	}
+
+	pool, err := parseClass(rawClass)
+	if err != nil {
+		return class, err
+	}
+
+	class.pool, class.count = pool, count
+
+	return class, nil
+}
+
+// splitPattern splits a pattern body by separators outside of custom sets,
+// so "2:[a/b]/3" is split into "2:[a/b]" and "3"
+func splitPattern(body string) []string {
+	var parts []string
+	var inSet bool
+	var start int
+	for i := 0; i < len(body); i++ {
+		switch {
+		case body[i] == '\\':
+			i++
+		case body[i] == classSetOpen && !inSet:
+			inSet = true
+		case body[i] == classSetClose && inSet:
+			inSet = false
+		case !inSet && strings.HasPrefix(body[i:], patternSeparator):
+			parts = append(parts, body[start:i])
+			start = i + len(patternSeparator)
+		}
+	}
+
+	return append(parts, body[start:])
+}
+
+// parseCharRange parses an exact number of characters: "5", or a range: "3-8"
+func parseCharRange(str string) (charRange, error) {
+	var count charRange
+	rawMin, rawMax := str, str
+	if i := strings.Index(str, patternRangeSeparator); i != -1 {
+		rawMin, rawMax = str[:i], str[i+len(patternRangeSeparator):]
+	}
+
+	min, err := strconv.Atoi(rawMin)
+	if err != nil {
+		return count, err
+	}
+
+	max, err := strconv.Atoi(rawMax)
+	if err != nil {
+		return count, err
+	}
+
+	if min < 0 || min > max {
+		return count, fmt.Errorf("invalid range: %s", str)
+	}
+
+	count.min, count.max = min, max
+
+	return count, nil
+}
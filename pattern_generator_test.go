@@ -3,6 +3,7 @@ package goson
 import (
 	"fmt"
 	"math/rand"
+	"regexp"
 	"testing"
 	"time"
 
@@ -35,6 +36,18 @@ func Test_locatePattern(t *testing.T) {
 			start: 10,
 			end:   16,
 		},
+		{
+			name:  "positive multibyte prefix",
+			input: "ключ\\<<4/2/1>",
+			start: 10,
+			end:   16,
+		},
+		{
+			name:  "positive escaped backslash",
+			input: "\\\\<4/2/1>",
+			start: 2,
+			end:   8,
+		},
 		{
 			name:  "invalid #1",
 			input: "4/2/1>",
@@ -85,6 +98,11 @@ func Test_unescapeString(t *testing.T) {
 			input: "\\<field\\>",
 			want:  "<field>",
 		},
+		{
+			name:  "positive multibyte",
+			input: "при\\<вет\\\\",
+			want:  "при<вет\\",
+		},
 		{
 			name:  "positive #3",
 			input: "\\<\\<\\>\\>",
@@ -111,10 +129,12 @@ func Test_parsePattern(t *testing.T) {
 			name:  "positive full pattern",
 			input: "4/2/1",
 			want: strPattern{
-				letters:  4,
-				digits:   2,
-				specials: 1,
-				length:   7,
+				classes: []charClass{
+					{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+					{pool: []rune(bytePoolDigits), count: charRange{2, 2}},
+					{pool: []rune(bytePoolSpecials), count: charRange{1, 1}},
+				},
+				length: 7,
 			},
 			wantErr: false,
 		},
@@ -122,9 +142,11 @@ func Test_parsePattern(t *testing.T) {
 			name:  "positive without digits",
 			input: "4/2",
 			want: strPattern{
-				letters: 4,
-				digits:  2,
-				length:  6,
+				classes: []charClass{
+					{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+					{pool: []rune(bytePoolDigits), count: charRange{2, 2}},
+				},
+				length: 6,
 			},
 			wantErr: false,
 		},
@@ -132,16 +154,86 @@ func Test_parsePattern(t *testing.T) {
 			name:  "positive only letters",
 			input: "4",
 			want: strPattern{
-				letters: 4,
-				length:  4,
+				classes: []charClass{
+					{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+				},
+				length: 4,
+			},
+			wantErr: false,
+		},
+		{
+			name:  "positive ranges",
+			input: "3-8/0-2/1",
+			want: strPattern{
+				classes: []charClass{
+					{pool: []rune(bytePoolLetters), count: charRange{3, 8}},
+					{pool: []rune(bytePoolDigits), count: charRange{0, 2}},
+					{pool: []rune(bytePoolSpecials), count: charRange{1, 1}},
+				},
+				length: 11,
+			},
+			wantErr: false,
+		},
+		{
+			name:  "positive named classes",
+			input: "2-4:upper/3:digit",
+			want: strPattern{
+				classes: []charClass{
+					{pool: []rune("ABCDEFGHIJKLMNOPQRSTUVWXYZ"), count: charRange{2, 4}},
+					{pool: []rune(bytePoolDigits), count: charRange{3, 3}},
+				},
+				length: 7,
+			},
+			wantErr: false,
+		},
+		{
+			name:  "positive custom set",
+			input: "6:[A-F0-9]",
+			want: strPattern{
+				classes: []charClass{
+					{pool: []rune("ABCDEF0123456789"), count: charRange{6, 6}},
+				},
+				length: 6,
+			},
+			wantErr: false,
+		},
+		{
+			name:  "positive custom set with separator",
+			input: "2:[a/b]/1",
+			want: strPattern{
+				classes: []charClass{
+					{pool: []rune("a/b"), count: charRange{2, 2}},
+					{pool: []rune(bytePoolDigits), count: charRange{1, 1}},
+				},
+				length: 3,
 			},
 			wantErr: false,
 		},
+		{
+			name:    "unknown class",
+			input:   "4:octal",
+			wantErr: true,
+		},
+		{
+			name:    "unterminated custom set",
+			input:   "4:[a-f",
+			wantErr: true,
+		},
 		{
 			name:    "invalid pattern len",
 			input:   "4/2/1/",
 			wantErr: true,
 		},
+		{
+			name:    "invalid range",
+			input:   "8-3",
+			wantErr: true,
+		},
+		{
+			name:    "range is too long",
+			input:   "1-60/1-5",
+			wantErr: true,
+		},
 		{
 			name:    "empty value",
 			input:   "4//1",
@@ -187,56 +279,66 @@ func Test_buildPattern(t *testing.T) {
 			name:  "positive body only",
 			input: "<4/2/1>",
 			want: strPattern{
-				letters:  4,
-				digits:   2,
-				specials: 1,
-				length:   7,
+				classes: []charClass{
+					{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+					{pool: []rune(bytePoolDigits), count: charRange{2, 2}},
+					{pool: []rune(bytePoolSpecials), count: charRange{1, 1}},
+				},
+				length: 7,
 			},
 		},
 		{
 			name:  "positive with prefix",
 			input: "some prefix <4/2/1>",
 			want: strPattern{
-				prefix:   "some prefix ",
-				letters:  4,
-				digits:   2,
-				specials: 1,
-				length:   7,
+				prefix: "some prefix ",
+				classes: []charClass{
+					{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+					{pool: []rune(bytePoolDigits), count: charRange{2, 2}},
+					{pool: []rune(bytePoolSpecials), count: charRange{1, 1}},
+				},
+				length: 7,
 			},
 		},
 		{
 			name:  "positive with suffix",
 			input: "<4/2/1> some suffix",
 			want: strPattern{
-				suffix:   " some suffix",
-				letters:  4,
-				digits:   2,
-				specials: 1,
-				length:   7,
+				suffix: " some suffix",
+				classes: []charClass{
+					{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+					{pool: []rune(bytePoolDigits), count: charRange{2, 2}},
+					{pool: []rune(bytePoolSpecials), count: charRange{1, 1}},
+				},
+				length: 7,
 			},
 		},
 		{
 			name:  "positive with prefix and suffix",
 			input: "some prefix <4/2/1> some suffix",
 			want: strPattern{
-				prefix:   "some prefix ",
-				suffix:   " some suffix",
-				letters:  4,
-				digits:   2,
-				specials: 1,
-				length:   7,
+				prefix: "some prefix ",
+				suffix: " some suffix",
+				classes: []charClass{
+					{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+					{pool: []rune(bytePoolDigits), count: charRange{2, 2}},
+					{pool: []rune(bytePoolSpecials), count: charRange{1, 1}},
+				},
+				length: 7,
 			},
 		},
 		{
 			name:  "positive with escapes",
 			input: "I \\<3 U <4/2/1> 1 \\> 0",
 			want: strPattern{
-				prefix:   "I <3 U ",
-				suffix:   " 1 > 0",
-				letters:  4,
-				digits:   2,
-				specials: 1,
-				length:   7,
+				prefix: "I <3 U ",
+				suffix: " 1 > 0",
+				classes: []charClass{
+					{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+					{pool: []rune(bytePoolDigits), count: charRange{2, 2}},
+					{pool: []rune(bytePoolSpecials), count: charRange{1, 1}},
+				},
+				length: 7,
 			},
 		},
 		{
@@ -274,20 +376,20 @@ func Test_buildPattern(t *testing.T) {
 }
 
 func Test_getRandomCharFromSource(t *testing.T) {
-	seedTestDate()
+	rnd := newTestRand()
 
 	source := "0123456789"
 	target := "0261344689" // pre-generated string with a test date
 	for i := 0; i < len(target); i++ {
 		t.Run(fmt.Sprintf("positive %d", i+1), func(t *testing.T) {
-			assert.Equal(t, target[i], getRandomCharFromSource(source))
+			assert.Equal(t, rune(target[i]), getRandomCharFromSource(rnd, []rune(source)))
 		})
 	}
 }
 
-func seedTestDate() {
+func newTestRand() *rand.Rand {
 	date, _ := time.Parse("2006-01-02", "1975-02-24")
-	rand.Seed(date.Unix())
+	return rand.New(rand.NewSource(date.Unix()))
 }
 
 func Test_generator_generate(t *testing.T) {
@@ -299,42 +401,48 @@ func Test_generator_generate(t *testing.T) {
 		{
 			name: "positive #1",
 			pattern: strPattern{
-				letters:  4,
-				digits:   2,
-				specials: 1,
-				length:   7,
+				classes: []charClass{
+					{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+					{pool: []rune(bytePoolDigits), count: charRange{2, 2}},
+					{pool: []rune(bytePoolSpecials), count: charRange{1, 1}},
+				},
+				length: 7,
 			},
 			want: "wQET3~4",
 		},
 		{
 			name: "positive #2",
 			pattern: strPattern{
-				letters:  4,
-				digits:   2,
-				specials: 1,
-				length:   7,
+				classes: []charClass{
+					{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+					{pool: []rune(bytePoolDigits), count: charRange{2, 2}},
+					{pool: []rune(bytePoolSpecials), count: charRange{1, 1}},
+				},
+				length: 7,
 			},
 			want: "f)j0vx2",
 		},
 		{
 			name: "positive #3",
 			pattern: strPattern{
-				letters:  4,
-				digits:   2,
-				specials: 1,
-				length:   7,
+				classes: []charClass{
+					{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+					{pool: []rune(bytePoolDigits), count: charRange{2, 2}},
+					{pool: []rune(bytePoolSpecials), count: charRange{1, 1}},
+				},
+				length: 7,
 			},
 			want: "+UGyD83",
 		},
 	}
 
-	seedTestDate()
+	rnd := newTestRand()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			testGenerator := &generator{
 				pattern: tt.pattern,
 			}
-			got := testGenerator.generate()
+			got := testGenerator.generate(rnd)
 			assert.Equal(t, tt.want, got)
 		})
 	}
@@ -349,57 +457,65 @@ func Test_generator_Generate(t *testing.T) {
 		{
 			name: "body only",
 			pattern: strPattern{
-				letters:  4,
-				digits:   2,
-				specials: 1,
-				length:   7,
+				classes: []charClass{
+					{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+					{pool: []rune(bytePoolDigits), count: charRange{2, 2}},
+					{pool: []rune(bytePoolSpecials), count: charRange{1, 1}},
+				},
+				length: 7,
 			},
 			want: "wQET3~4",
 		},
 		{
 			name: "with prefix",
 			pattern: strPattern{
-				prefix:   "some prefix ",
-				letters:  4,
-				digits:   2,
-				specials: 1,
-				length:   7,
+				prefix: "some prefix ",
+				classes: []charClass{
+					{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+					{pool: []rune(bytePoolDigits), count: charRange{2, 2}},
+					{pool: []rune(bytePoolSpecials), count: charRange{1, 1}},
+				},
+				length: 7,
 			},
 			want: "some prefix f)j0vx2",
 		},
 		{
 			name: "with suffix",
 			pattern: strPattern{
-				suffix:   " some suffix",
-				letters:  4,
-				digits:   2,
-				specials: 1,
-				length:   7,
+				suffix: " some suffix",
+				classes: []charClass{
+					{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+					{pool: []rune(bytePoolDigits), count: charRange{2, 2}},
+					{pool: []rune(bytePoolSpecials), count: charRange{1, 1}},
+				},
+				length: 7,
 			},
 			want: "+UGyD83 some suffix",
 		},
 		{
 			name: "with prefix and suffix",
 			pattern: strPattern{
-				prefix:   "some prefix ",
-				suffix:   " some suffix",
-				letters:  4,
-				digits:   2,
-				specials: 1,
-				length:   7,
+				prefix: "some prefix ",
+				suffix: " some suffix",
+				classes: []charClass{
+					{pool: []rune(bytePoolLetters), count: charRange{4, 4}},
+					{pool: []rune(bytePoolDigits), count: charRange{2, 2}},
+					{pool: []rune(bytePoolSpecials), count: charRange{1, 1}},
+				},
+				length: 7,
 			},
 			want: "some prefix b$Tl7G1 some suffix",
 		},
 	}
 
-	seedTestDate()
+	rnd := newTestRand()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			testGenerator := &generator{
 				pattern: tt.pattern,
 			}
 
-			got := testGenerator.Generate()
+			got := testGenerator.Generate(rnd)
 			assert.Equal(t, tt.want, got)
 		})
 	}
@@ -431,6 +547,20 @@ func Test_newPatternGenerator(t *testing.T) {
 			name:  "positive with escaped suffix",
 			input: "\\<prefix\\> <4/2/1>",
 		},
+		{
+			name:  "positive ordered segments",
+			input: "<2:upper>-<4:digit> \\<x\\>",
+		},
+		{
+			name:    "invalid nested block",
+			input:   "<2:upper><4/<1>",
+			wantErr: true,
+		},
+		{
+			name:    "invalid unclosed segment",
+			input:   "<2:upper>-<4",
+			wantErr: true,
+		},
 		{
 			name:    "invalid pattern #1",
 			input:   "<4/2/1",
@@ -473,3 +603,118 @@ func Test_newPatternGenerator(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseCharRange(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    charRange
+		wantErr bool
+	}{
+		{
+			name:  "positive exact",
+			input: "5",
+			want:  charRange{5, 5},
+		},
+		{
+			name:  "positive range",
+			input: "0-12",
+			want:  charRange{0, 12},
+		},
+		{
+			name:    "inverted range",
+			input:   "5-1",
+			wantErr: true,
+		},
+		{
+			name:    "negative number",
+			input:   "-1",
+			wantErr: true,
+		},
+		{
+			name:    "open range",
+			input:   "1-",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCharRange(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_generator_generate_ranges(t *testing.T) {
+	testGenerator := &generator{
+		pattern: strPattern{
+			classes: []charClass{
+				{pool: []rune(bytePoolLetters), count: charRange{3, 8}},
+				{pool: []rune(bytePoolDigits), count: charRange{0, 2}},
+			},
+			length: 10,
+		},
+	}
+
+	rnd := newTestRand()
+	lengths := make(map[int]bool)
+	for i := 0; i < 200; i++ {
+		got := testGenerator.generate(rnd)
+		letters := len(regexp.MustCompile("[a-zA-Z]").FindAllString(got, -1))
+		digits := len(regexp.MustCompile("[0-9]").FindAllString(got, -1))
+		assert.Equal(t, len(got), letters+digits)
+		assert.True(t, letters >= 3 && letters <= 8)
+		assert.True(t, digits >= 0 && digits <= 2)
+		lengths[len(got)] = true
+	}
+	assert.Len(t, lengths, 8)
+}
+
+func Test_splitPatternBlocks(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "single block",
+			input: "prefix <4/2/1> suffix",
+			want:  []string{"prefix <4/2/1> suffix"},
+		},
+		{
+			name:  "ordered segments",
+			input: "<2:upper>-<4:digit>!",
+			want:  []string{"<2:upper>", "-<4:digit>!"},
+		},
+		{
+			name:  "escaped brackets",
+			input: "\\<a\\><1>\\<b\\><2>",
+			want:  []string{"\\<a\\><1>", "\\<b\\><2>"},
+		},
+		{
+			name:  "unclosed tail",
+			input: "<1>-<2",
+			want:  []string{"<1>", "-<2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, splitPatternBlocks(tt.input))
+		})
+	}
+}
+
+func Test_segmentedGenerator_Generate(t *testing.T) {
+	testGenerator, err := newPatternGenerator("SKU-<2:upper><4:digit>-<3:[a-c]>")
+	require.NoError(t, err)
+
+	rnd := newTestRand()
+	for i := 0; i < 50; i++ {
+		assert.Regexp(t, "^SKU-[A-Z]{2}[0-9]{4}-[a-c]{3}$", testGenerator.Generate(rnd))
+	}
+}
@@ -1,35 +1,52 @@
 package goson
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"math/rand"
-	"time"
+	"sync"
 )
 
-// Processor constructs a parser and force it to parse an input bytes
+// Processor constructs a parser and force it to parse an input bytes.
+// It is safe for a concurrent use, as long as custom keyword fields are.
+// Records generated concurrently by Generate share the processor's source of randomness,
+// so their order isn't reproducible, use GenerateParallel with WithOrdered for that.
+// Values of seq, counter and unique fields and times of a clock that steps per record
+// are taken in the order records are generated
 type Processor interface {
 	Generate() []byte
+	GenerateRecord() ([]byte, error)
+	GenerateTo(ctx context.Context, w io.Writer, n int, opts ...StreamOption) error
+	GenerateParallel(ctx context.Context, w io.Writer, n, workers int, opts ...StreamOption) error
+	Seed() int64
+	Counters() map[string]int64
+	SetCounters(values map[string]int64) error
+	ResetCounters()
 }
 
 type processor struct {
-	fields map[string]ParsedField
+	fields   []ParsedField     // top-level fields in the order of the template
+	links    []*dependencyLink // dependent fields in the order of resolution
+	program  *program          // compiled fields, nil for templates with references that can't be compiled
+	encoders sync.Pool         // reusable encoders of the compiled program
+	clock    Clock             // a clock of time-based fields, nil for the system one
+	counters *counterSet       // counters of seq and counter fields
+	stateful bool              // records depend on the ones generated before them, see keepsState
+	seed     int64
+	rand     *rand.Rand
 }
 
-// Generate generates and returns a record according to an input fields
+// Generate generates and returns a record according to an input fields.
+// Templates are compiled into a program that writes JSON straight into a reusable buffer,
+// so the returned slice is the only allocation of a record. Plain references like "_go:_password" are compiled too,
+// while templates with interpolated references, uuid:v5 names taken from fields, references into repeat items
+// or references to values that contain references are generated as interface{} values and marshaled.
+// It panics when a record fails to generate, e.g. a unique field runs out of values with *UniqueError,
+// use GenerateRecord to get the error instead
 func (processor *processor) Generate() []byte {
-	fields := make(map[string]interface{})
-	for _, field := range processor.fields {
-		fields[field.Name()] = field.Value()
-	}
-
-	for _, field := range processor.fields {
-		switch field.(type) {
-		case *referenceField:
-			fields[field.Name()] = fields[field.Value().(string)]
-		}
-	}
-
-	b, err := json.Marshal(fields)
+	b, err := processor.GenerateRecord()
 	if err != nil {
 		panic(err)
 	}
@@ -37,32 +54,165 @@ func (processor *processor) Generate() []byte {
 	return b
 }
 
-// New builds a Processor with a default set of keywords
-func New(body []byte) (Processor, error) {
-	defaultParser := newParser()
-	fields, err := defaultParser.Parse(body)
+// GenerateRecord generates and returns a record as Generate does, but returns an error when the record fails,
+// e.g. *UniqueError when a unique field runs out of values
+func (processor *processor) GenerateRecord() ([]byte, error) {
+	defer processor.tick()
+
+	if processor.program == nil {
+		return processor.generateRecord(processor.rand)
+	}
+
+	enc := processor.encoder()
+	defer processor.encoders.Put(enc)
+
+	buf, err := processor.appendWith(enc, enc.buf[:0], processor.rand)
+	enc.buf = buf
 	if err != nil {
 		return nil, err
 	}
 
-	rand.Seed(time.Now().Unix())
+	return append(make([]byte, 0, len(buf)), buf...), nil
+}
+
+// appendRecord appends a record generated with rnd to dst
+func (processor *processor) appendRecord(dst []byte, rnd *rand.Rand) ([]byte, error) {
+	defer processor.tick()
 
-	return &processor{
-		fields: fields,
-	}, nil
+	if processor.program == nil {
+		record, err := processor.generateRecord(rnd)
+		if err != nil {
+			return dst, err
+		}
+
+		return append(dst, record...), nil
+	}
+
+	enc := processor.encoder()
+	defer processor.encoders.Put(enc)
+
+	buf := enc.buf
+	dst, err := processor.appendWith(enc, dst, rnd)
+	enc.buf = buf
+
+	return dst, err
 }
 
-// NewWithCustomKeywords allows to build a Processor that can parse and handle a custom set of keywords
-func NewWithCustomKeywords(body []byte, keywordSet map[string]NewFieldFunc) (Processor, error) {
-	defaultParser := newParserWithCustomKeywords(keywordSet)
+// appendWith runs the compiled program with an encoder writing into dst
+func (processor *processor) appendWith(enc *encoder, dst []byte, rnd *rand.Rand) ([]byte, error) {
+	enc.rnd, enc.buf, enc.err = rnd, dst, nil
+	enc.begin(processor.program)
+	processor.program.run(enc)
+	enc.fillHoles(len(dst))
+
+	return enc.buf, enc.err
+}
+
+// keepsState reports whether a record depends on the records generated before it:
+// the template has seq, counter or unique fields or its clock steps per record
+func (processor *processor) keepsState() bool {
+	_, stepping := processor.clock.(recordClock)
+
+	return processor.stateful || stepping
+}
+
+// tick notifies a clock that steps per record
+func (processor *processor) tick() {
+	if clock, ok := processor.clock.(recordClock); ok {
+		clock.advance()
+	}
+}
+
+func (processor *processor) encoder() *encoder {
+	if enc, ok := processor.encoders.Get().(*encoder); ok {
+		return enc
+	}
+
+	return &encoder{}
+}
+
+// generateRecord builds a record of interface{} values, resolves references and marshals it
+func (processor *processor) generateRecord(rnd *rand.Rand) ([]byte, error) {
+	record := newOrderedObject(len(processor.fields))
+	for _, field := range processor.fields {
+		if value, ok := entryValue(field, rnd); ok {
+			record.set(field.Name(), value)
+		}
+	}
+
+	for _, link := range processor.links {
+		link.resolve(record)
+	}
+
+	b, err := json.Marshal(record)
+	var uniqueErr *UniqueError
+	if errors.As(err, &uniqueErr) {
+		return nil, uniqueErr
+	}
+
+	return b, err
+}
+
+// Seed returns the seed of the processor's source of randomness
+func (processor *processor) Seed() int64 {
+	return processor.seed
+}
+
+// Counters returns a snapshot of the next values of sequences and counters.
+// Sequences are named after the paths of their fields, e.g. "id" or "orders[1].id",
+// sequences of ${...} expressions get their numbers within the string, e.g. "label#2" for "_go:${seq}-${seq}",
+// counters keep the names given in a template
+func (processor *processor) Counters() map[string]int64 {
+	return processor.counters.snapshot()
+}
+
+// SetCounters sets the next values of sequences and counters, e.g. to restore a snapshot taken with Counters
+func (processor *processor) SetCounters(values map[string]int64) error {
+	return processor.counters.restore(values)
+}
+
+// ResetCounters restarts sequences and counters from their start values
+func (processor *processor) ResetCounters() {
+	processor.counters.reset()
+}
+
+// New builds a Processor with a default set of keywords, see Generate for the templates that are compiled
+func New(body []byte, opts ...Option) (Processor, error) {
+	options := newOptions(opts)
+	if options.prefix == "" {
+		return nil, errors.New("directive prefix must not be empty")
+	}
+
+	counters := newCounterSet()
+	defaultParser := newParser(options, counters)
 	fields, err := defaultParser.Parse(body)
 	if err != nil {
 		return nil, err
 	}
 
-	rand.Seed(time.Now().Unix())
+	links, err := defaultParser.Link(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled, err := compileTemplate(fields, links)
+	if err != nil {
+		return nil, err
+	}
 
 	return &processor{
-		fields: fields,
+		fields:   fields,
+		links:    links,
+		program:  compiled,
+		clock:    options.clock,
+		counters: counters,
+		stateful: defaultParser.stateful,
+		seed:     options.seed,
+		rand:     rand.New(newLockedSource(options.seed)),
 	}, nil
 }
+
+// NewWithCustomKeywords allows to build a Processor that can parse and handle a custom set of keywords
+func NewWithCustomKeywords(body []byte, keywordSet map[string]NewFieldFunc, opts ...Option) (Processor, error) {
+	return New(body, append([]Option{WithKeywords(keywordSet)}, opts...)...)
+}
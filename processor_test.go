@@ -1,6 +1,7 @@
 package goson
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -111,8 +112,7 @@ func newTestDummyField(key, _ string) (ParsedField, error) {
 }
 
 func TestNewWithCustomKeywords(t *testing.T) {
-	keywordSet := getDefaultKeywordSet()
-	keywordSet["dummy"] = newTestDummyField
+	keywordSet := map[string]NewFieldFunc{"dummy": newTestDummyField}
 	body := []byte(`{"dummy":"_go:dummy"}`)
 	expect := []byte(`{"dummy":"dummy"}`)
 
@@ -124,8 +124,48 @@ func TestNewWithCustomKeywords(t *testing.T) {
 	})
 }
 
+type testEchoField struct {
+	name string
+	args []interface{}
+}
+
+func (testEchoField *testEchoField) Name() string {
+	return testEchoField.name
+}
+
+func (testEchoField *testEchoField) Value() interface{} {
+	return testEchoField.args
+}
+
+func newTestEchoField(key string, call *KeywordCall) (ParsedField, error) {
+	if err := call.Arity(1, 2); err != nil {
+		return nil, err
+	}
+
+	return &testEchoField{
+		name: key,
+		args: call.Args,
+	}, nil
+}
+
+func TestWithKeywordFuncs(t *testing.T) {
+	keywordSet := map[string]KeywordFunc{"echo": newTestEchoField}
+
+	t.Run("positive", func(t *testing.T) {
+		body := []byte(`{"echo":"_go:echo(1, \"a\")"}`)
+		testProcessor, err := New(body, WithKeywordFuncs(keywordSet))
+		require.NoError(t, err)
+		assert.Equal(t, []byte(`{"echo":[1,"a"]}`), testProcessor.Generate())
+	})
+
+	t.Run("invalid arity", func(t *testing.T) {
+		body := []byte(`{"echo":"_go:echo(1, 2, 3)"}`)
+		_, err := New(body, WithKeywordFuncs(keywordSet))
+		require.EqualError(t, err, "field echo at offset 8: keyword echo expects at most 2 arguments, got 3")
+	})
+}
+
 func Test_processor_Generate(t *testing.T) {
-	seedTestDate()
 	defaultBody := []byte(`
 		{
 			"email": "_go:<5/1>@test.com",
@@ -147,8 +187,7 @@ func Test_processor_Generate(t *testing.T) {
 
 	customBody := []byte(`{"dummy": "_go:dummy"}`)
 	expect := []byte(`{"dummy":"dummy"}`)
-	keywordSet := getDefaultKeywordSet()
-	keywordSet["dummy"] = newTestDummyField
+	keywordSet := map[string]NewFieldFunc{"dummy": newTestDummyField}
 
 	customProcessor, err := NewWithCustomKeywords(customBody, keywordSet)
 	t.Run("positive custom processor", func(t *testing.T) {
@@ -157,3 +196,279 @@ func Test_processor_Generate(t *testing.T) {
 		assert.Equal(t, expect, got)
 	})
 }
+
+func Test_processor_Generate_nested(t *testing.T) {
+	body := []byte(`
+		{
+			"password": "_go:<5/2/1>",
+			"user": {
+				"email": "_go:<5/1>@test.com",
+				"credentials": {
+					"password": "_go:_password"
+				},
+				"tags": ["_go:<3>", "static", 1, {"password": "_go:_password"}]
+			}
+		}
+	`)
+	testProcessor, err := New(body)
+	require.NoError(t, err)
+
+	var got struct {
+		Password string `json:"password"`
+		User     struct {
+			Email       string `json:"email"`
+			Credentials struct {
+				Password string `json:"password"`
+			} `json:"credentials"`
+			Tags []interface{} `json:"tags"`
+		} `json:"user"`
+	}
+	require.NoError(t, json.Unmarshal(testProcessor.Generate(), &got))
+
+	assert.Len(t, got.Password, 8)
+	assert.Regexp(t, "^[a-zA-Z0-9]{6}@test.com$", got.User.Email)
+	assert.Equal(t, got.Password, got.User.Credentials.Password)
+	require.Len(t, got.User.Tags, 4)
+	assert.Regexp(t, "^[a-zA-Z]{3}$", got.User.Tags[0])
+	assert.Equal(t, "static", got.User.Tags[1])
+	assert.Equal(t, float64(1), got.User.Tags[2])
+	assert.Equal(t, map[string]interface{}{"password": got.Password}, got.User.Tags[3])
+}
+
+func Test_processor_Generate_uuid(t *testing.T) {
+	body := []byte(`
+		{
+			"email": "_go:<5/1>@test.com",
+			"v4": "_go:uuid",
+			"v7": "_go:uuid:v7",
+			"v5": "_go:uuid:v5:_email",
+			"v5_copy": "_go:uuid:v5:_email"
+		}
+	`)
+	testProcessor, err := New(body)
+	require.NoError(t, err)
+
+	var prev map[string]string
+	for i := 0; i < 100; i++ {
+		var got map[string]string
+		require.NoError(t, json.Unmarshal(testProcessor.Generate(), &got))
+
+		assert.Regexp(t, "^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$", got["v4"])
+		assert.Regexp(t, "^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$", got["v7"])
+		assert.Equal(t, newUUIDv5(uuidNamespaceDNS, got["email"]).String(), got["v5"])
+		assert.Equal(t, got["v5"], got["v5_copy"])
+		if prev != nil {
+			assert.NotEqual(t, prev["v4"], got["v4"])
+			assert.Less(t, prev["v7"], got["v7"])
+		}
+		prev = got
+	}
+}
+
+func TestWithSeed(t *testing.T) {
+	body := []byte(`
+		{
+			"id": "_go:uuid",
+			"email": "_go:<5/1>@test.com",
+			"password": "_go:<5/2/1>",
+			"confirm_password": "_go:_password",
+			"user": {
+				"login": "_go:<8>",
+				"tags": ["_go:<3>", "_go:<3/3>"]
+			}
+		}
+	`)
+	first, err := New(body, WithSeed(42))
+	require.NoError(t, err)
+	second, err := New(body, WithSeed(42))
+	require.NoError(t, err)
+	other, err := New(body, WithSeed(43))
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(42), first.Seed())
+	for i := 0; i < 10; i++ {
+		got := first.Generate()
+		assert.Equal(t, got, second.Generate())
+		assert.NotEqual(t, got, other.Generate())
+	}
+}
+
+func Test_processor_GenerateRecord(t *testing.T) {
+	bodies := map[string][]byte{
+		"compiled":   []byte(`{"email": "_go:<5/1>@test.com", "tags": ["_go:repeat(2)", "_go:<3>"]}`),
+		"references": []byte(`{"password": "_go:<5/2/1>", "confirm_password": "_go:_password"}`),
+	}
+	for name, body := range bodies {
+		t.Run(name, func(t *testing.T) {
+			first, err := New(body, WithSeed(42))
+			require.NoError(t, err)
+			second, err := New(body, WithSeed(42))
+			require.NoError(t, err)
+
+			for i := 0; i < 5; i++ {
+				got, err := first.GenerateRecord()
+				require.NoError(t, err)
+				assert.Equal(t, second.Generate(), got)
+			}
+		})
+	}
+}
+
+func TestWithPrefix(t *testing.T) {
+	body := []byte(`
+		{
+			"doc": "_go:<5> is a pattern",
+			"note": "see _go:docs",
+			"code": "$gen:<3:digit>",
+			"escaped": "\\$gen:<3>",
+			"items": ["$gen:repeat(2)", "$gen:_code"]
+		}
+	`)
+	testProcessor, err := New(body, WithPrefix("$gen:"), WithSeed(1))
+	require.NoError(t, err)
+
+	var got struct {
+		Doc     string   `json:"doc"`
+		Note    string   `json:"note"`
+		Code    string   `json:"code"`
+		Escaped string   `json:"escaped"`
+		Items   []string `json:"items"`
+	}
+	require.NoError(t, json.Unmarshal(testProcessor.Generate(), &got))
+	assert.Equal(t, "_go:<5> is a pattern", got.Doc)
+	assert.Equal(t, "see _go:docs", got.Note)
+	assert.Regexp(t, "^[0-9]{3}$", got.Code)
+	assert.Equal(t, "$gen:<3>", got.Escaped)
+	assert.Equal(t, []string{got.Code, got.Code}, got.Items)
+
+	_, err = New(body, WithPrefix(""))
+	require.Error(t, err)
+}
+
+func TestNew_seed(t *testing.T) {
+	body := []byte(`{"password": "_go:<5/2/1>"}`)
+	testProcessor, err := New(body)
+	require.NoError(t, err)
+
+	replayed, err := New(body, WithSeed(testProcessor.Seed()))
+	require.NoError(t, err)
+	assert.Equal(t, testProcessor.Generate(), replayed.Generate())
+}
+
+func Test_processor_Generate_repeat(t *testing.T) {
+	body := []byte(`
+		{
+			"currency": "_go:<3>",
+			"items": [
+				"_go:repeat(3, 10)",
+				{
+					"sku": "_go:<4/4>",
+					"currency": "_go:_currency"
+				}
+			],
+			"empty": ["_go:repeat(0)", "_go:<3>"]
+		}
+	`)
+	testProcessor, err := New(body, WithSeed(7))
+	require.NoError(t, err)
+
+	lengths := make(map[int]bool)
+	for i := 0; i < 50; i++ {
+		var got struct {
+			Currency string `json:"currency"`
+			Items    []struct {
+				SKU      string `json:"sku"`
+				Currency string `json:"currency"`
+			} `json:"items"`
+			Empty []string `json:"empty"`
+		}
+		require.NoError(t, json.Unmarshal(testProcessor.Generate(), &got))
+
+		require.True(t, len(got.Items) >= 3 && len(got.Items) <= 10)
+		lengths[len(got.Items)] = true
+		skus := make(map[string]bool)
+		for _, item := range got.Items {
+			assert.Len(t, item.SKU, 8)
+			assert.Equal(t, got.Currency, item.Currency)
+			skus[item.SKU] = true
+		}
+		assert.Len(t, skus, len(got.Items))
+		assert.NotNil(t, got.Empty)
+		assert.Empty(t, got.Empty)
+	}
+	assert.Greater(t, len(lengths), 1)
+}
+
+func Test_processor_Generate_references(t *testing.T) {
+	body := []byte(`
+		{
+			"a": "_go:_b",
+			"b": "_go:_c",
+			"c": "_go:<8>",
+			"city": "_go:_user.address.city",
+			"user": {
+				"address": {"city": "_go:<6>"}
+			},
+			"first_sku": "_go:_items[0].sku",
+			"items": ["_go:repeat(1, 3)", {"sku": "_go:<5>", "city": "_go:_city"}],
+			"id": "_go:uuid:v5:_user.address.city"
+		}
+	`)
+	testProcessor, err := New(body)
+	require.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		var got struct {
+			A    string `json:"a"`
+			B    string `json:"b"`
+			C    string `json:"c"`
+			City string `json:"city"`
+			User struct {
+				Address struct {
+					City string `json:"city"`
+				} `json:"address"`
+			} `json:"user"`
+			FirstSKU string `json:"first_sku"`
+			Items    []struct {
+				SKU  string `json:"sku"`
+				City string `json:"city"`
+			} `json:"items"`
+			ID string `json:"id"`
+		}
+		require.NoError(t, json.Unmarshal(testProcessor.Generate(), &got))
+
+		assert.Len(t, got.C, 8)
+		assert.Equal(t, got.C, got.B)
+		assert.Equal(t, got.C, got.A)
+		assert.Len(t, got.City, 6)
+		assert.Equal(t, got.User.Address.City, got.City)
+		require.NotEmpty(t, got.Items)
+		assert.Equal(t, got.Items[0].SKU, got.FirstSKU)
+		for _, item := range got.Items {
+			assert.Equal(t, got.City, item.City)
+		}
+		assert.Equal(t, newUUIDv5(uuidNamespaceDNS, got.City).String(), got.ID)
+	}
+}
+
+func Test_processor_Generate_keyOrder(t *testing.T) {
+	body := []byte(`
+		{
+			"zeta": "z",
+			"alpha": {
+				"yankee": 1,
+				"bravo": ["x", {"whiskey": true, "charlie": null}],
+				"xray": "_go:_zeta"
+			},
+			"mike": "_go:_alpha.bravo[1]"
+		}
+	`)
+	expect := `{"zeta":"z","alpha":{"yankee":1,"bravo":["x",{"whiskey":true,"charlie":null}],"xray":"z"},` +
+		`"mike":{"whiskey":true,"charlie":null}}`
+
+	testProcessor, err := New(body)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, expect, string(testProcessor.Generate()))
+	}
+}
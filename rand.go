@@ -0,0 +1,60 @@
+package goson
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// lockedSource is a rand.Source64 that is safe for a concurrent use
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source64
+}
+
+func (lockedSource *lockedSource) Int63() int64 {
+	lockedSource.mu.Lock()
+	defer lockedSource.mu.Unlock()
+
+	return lockedSource.src.Int63()
+}
+
+func (lockedSource *lockedSource) Uint64() uint64 {
+	lockedSource.mu.Lock()
+	defer lockedSource.mu.Unlock()
+
+	return lockedSource.src.Uint64()
+}
+
+func (lockedSource *lockedSource) Seed(seed int64) {
+	lockedSource.mu.Lock()
+	defer lockedSource.mu.Unlock()
+
+	lockedSource.src.Seed(seed)
+}
+
+func newLockedSource(seed int64) rand.Source64 {
+	return &lockedSource{
+		src: rand.NewSource(seed).(rand.Source64),
+	}
+}
+
+// fallbackRand is used when a field value is requested outside a Processor,
+// e.g. by calling ParsedField.Value directly
+var fallbackRand = rand.New(newLockedSource(time.Now().UnixNano()))
+
+// randomField is a field that draws its randomness from the processor's source,
+// so the same seed reproduces the same records
+type randomField interface {
+	ParsedField
+	valueWith(rnd *rand.Rand) interface{}
+}
+
+// fieldValue generates a value of the field with a given source of randomness
+func fieldValue(field ParsedField, rnd *rand.Rand) interface{} {
+	if field, ok := field.(randomField); ok {
+		return field.valueWith(rnd)
+	}
+
+	return field.Value()
+}
@@ -0,0 +1,412 @@
+package goson
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathSegment is an object key or an array index.
+// Items of a repeatField have no fixed index and are addressed by anyIndex
+type pathSegment struct {
+	key   string
+	index int
+}
+
+const (
+	keySegment = -1
+	anyIndex   = -2
+)
+
+func (segment pathSegment) isIndex() bool {
+	return segment.index != keySegment
+}
+
+// matches reports whether two segments may address the same value
+func (segment pathSegment) matches(other pathSegment) bool {
+	if segment.isIndex() && other.isIndex() {
+		return segment.index == other.index || segment.index == anyIndex || other.index == anyIndex
+	}
+
+	return segment == other
+}
+
+// fieldPath is a location of a value in a record, e.g. "user.address.city" or "items[0].sku"
+type fieldPath []pathSegment
+
+func (path fieldPath) String() string {
+	var b strings.Builder
+	for i, segment := range path {
+		switch {
+		case segment.index == anyIndex:
+			b.WriteString("[*]")
+		case segment.isIndex():
+			b.WriteString("[" + strconv.Itoa(segment.index) + "]")
+		default:
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(segment.key)
+		}
+	}
+
+	return b.String()
+}
+
+// key returns an unambiguous form of the path: keys are quoted, so "a.b" and a key "b" of "a" differ
+func (path fieldPath) key() string {
+	var b strings.Builder
+	for _, segment := range path {
+		switch {
+		case segment.index == anyIndex:
+			b.WriteString("[*]")
+		case segment.isIndex():
+			b.WriteString("[" + strconv.Itoa(segment.index) + "]")
+		default:
+			b.WriteString(strconv.Quote(segment.key))
+		}
+	}
+
+	return b.String()
+}
+
+func (path fieldPath) child(segment pathSegment) fieldPath {
+	child := make(fieldPath, len(path), len(path)+1)
+	copy(child, path)
+
+	return append(child, segment)
+}
+
+// overlaps reports whether one of the paths is a prefix of the other,
+// so the value at one path contains or is contained by the value at the other
+func (path fieldPath) overlaps(other fieldPath) bool {
+	n := len(path)
+	if len(other) < n {
+		n = len(other)
+	}
+
+	for i := 0; i < n; i++ {
+		if !path[i].matches(other[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseFieldPath parses a reference path: dot separated keys with optional array indexes
+func parseFieldPath(raw string) (fieldPath, error) {
+	var path fieldPath
+	for _, part := range strings.Split(raw, ".") {
+		key := part
+		if i := strings.IndexByte(part, '['); i != -1 {
+			key, part = part[:i], part[i:]
+		} else {
+			part = ""
+		}
+
+		if key == "" {
+			return nil, fmt.Errorf("invalid reference path: %s", raw)
+		}
+		path = append(path, pathSegment{key: key, index: keySegment})
+
+		for part != "" {
+			end := strings.IndexByte(part, ']')
+			if part[0] != '[' || end == -1 {
+				return nil, fmt.Errorf("invalid reference path: %s", raw)
+			}
+
+			index, err := strconv.Atoi(part[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid array index in reference path: %s", raw)
+			}
+			path = append(path, pathSegment{index: index})
+			part = part[end+1:]
+		}
+	}
+
+	return path, nil
+}
+
+// dependencyLink binds a dependentField to its location in a record and to the locations of its dependencies
+type dependencyLink struct {
+	field    dependentField
+	location fieldPath
+	targets  []fieldPath
+	nullable bool // the field is optional and keeps null when it isn't generated
+}
+
+// resolve computes the value of the dependent field from the record and writes it into its location
+func (link *dependencyLink) resolve(record *orderedObject) {
+	values := make([]interface{}, len(link.targets))
+	for i, target := range link.targets {
+		values[i] = lookupPath(record, target)
+	}
+
+	updatePath(record, link.location, func(current interface{}) interface{} {
+		switch field := link.field.(type) {
+		case draftField:
+			return field.complete(current, values)
+		default:
+			if link.nullable && current == nil {
+				return nil
+			}
+
+			return field.valueFrom(values)
+		}
+	})
+}
+
+// linkDependencies finds every dependentField, nested ones included, checks that their dependencies exist
+// and returns them in the order of resolution: every field goes after the fields its dependencies are built from.
+// It reports every malformed or unknown reference, or the first reference cycle
+func linkDependencies(fields []ParsedField) ([]*dependencyLink, []error) {
+	linker := &linker{}
+	for _, field := range fields {
+		if field != nil {
+			linker.collect(field, fieldPath{{key: field.Name(), index: keySegment}})
+		}
+	}
+
+	// link i depends on link j when one of its targets contains or is contained by the location of j
+	links := linker.links
+	edges := make([][]int, len(links))
+	for i, link := range links {
+		for j, other := range links {
+			for _, target := range link.targets {
+				if target.overlaps(other.location) {
+					edges[i] = append(edges[i], j)
+					break
+				}
+			}
+		}
+	}
+
+	ordered, err := sortLinks(links, edges)
+	if err != nil {
+		return nil, append(linker.errs, err)
+	}
+
+	for _, link := range ordered {
+		for _, target := range link.targets {
+			if err := locatePath(fields, target); err != nil {
+				linker.errs = append(linker.errs, &ReferenceError{
+					Path:      link.location.String(),
+					Offset:    -1,
+					Reference: target.String(),
+					Err:       err,
+				})
+			}
+		}
+	}
+
+	if len(linker.errs) > 0 {
+		return nil, linker.errs
+	}
+
+	return ordered, nil
+}
+
+// linker collects dependent fields of a parsed template
+type linker struct {
+	links []*dependencyLink
+	errs  []error
+}
+
+func (linker *linker) collect(field ParsedField, location fieldPath) {
+	linker.collectField(field, location, false)
+}
+
+func (linker *linker) collectField(field ParsedField, location fieldPath, nullable bool) {
+	switch field := field.(type) {
+	case *optionalField:
+		linker.collectField(field.field, location, field.presence.nullable)
+	case dependentField:
+		link := &dependencyLink{
+			field:    field,
+			location: location,
+			nullable: nullable,
+		}
+		for _, dependency := range field.dependencies() {
+			target, err := parseFieldPath(dependency)
+			if err != nil {
+				linker.errs = append(linker.errs, &SyntaxError{
+					Path:   location.String(),
+					Offset: -1,
+					Err:    err,
+				})
+				return
+			}
+			link.targets = append(link.targets, target)
+		}
+		linker.links = append(linker.links, link)
+	case *objectField:
+		for _, child := range field.fields {
+			linker.collect(child, location.child(pathSegment{key: child.Name(), index: keySegment}))
+		}
+	case *arrayField:
+		for i, item := range field.items {
+			linker.collect(item, location.child(pathSegment{index: i}))
+		}
+	case *repeatField:
+		linker.collect(field.item, location.child(pathSegment{index: anyIndex}))
+	}
+}
+
+// sortLinks orders links topologically with a depth-first search
+func sortLinks(links []*dependencyLink, edges [][]int) ([]*dependencyLink, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make([]int, len(links))
+	ordered := make([]*dependencyLink, 0, len(links))
+	var stack []int
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			cycle := make([]string, 0, len(stack)+1)
+			for j := len(stack) - 1; j >= 0; j-- {
+				cycle = append(cycle, links[stack[j]].location.String())
+				if stack[j] == i {
+					break
+				}
+			}
+			for l, r := 0, len(cycle)-1; l < r; l, r = l+1, r-1 {
+				cycle[l], cycle[r] = cycle[r], cycle[l]
+			}
+
+			return &ReferenceError{
+				Path:      links[i].location.String(),
+				Offset:    -1,
+				Reference: links[i].targets[0].String(),
+				Err:       fmt.Errorf("reference cycle: %s -> %s", strings.Join(cycle, " -> "), links[i].location),
+			}
+		}
+
+		state[i] = visiting
+		stack = append(stack, i)
+		for _, j := range edges[i] {
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = visited
+		ordered = append(ordered, links[i])
+
+		return nil
+	}
+
+	for i := range links {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
+
+// locatePath checks that a path points to a value of a parsed template.
+// Paths that go through a reference are followed to the referenced field
+func locatePath(fields []ParsedField, path fieldPath) error {
+	root := &objectField{
+		fields: fields,
+	}
+	field, ok := root.field(path[0].key)
+	if !ok || path[0].isIndex() {
+		return fmt.Errorf("unknown reference: %s", path)
+	}
+
+	for i := 1; i < len(path); i++ {
+		segment := path[i]
+		switch current := unwrapOptional(field).(type) {
+		case *objectField:
+			if field, ok = current.field(segment.key); !ok || segment.isIndex() {
+				return fmt.Errorf("unknown reference: %s", path)
+			}
+		case *arrayField:
+			if !segment.isIndex() || segment.index >= len(current.items) {
+				return fmt.Errorf("unknown reference: %s", path)
+			}
+			field = current.items[segment.index]
+		case *repeatField:
+			if !segment.isIndex() || segment.index >= current.max {
+				return fmt.Errorf("unknown reference: %s", path)
+			}
+			field = current.item
+		case *referenceField:
+			target, err := parseFieldPath(current.referenceTo)
+			if err != nil {
+				return err
+			}
+
+			return locatePath(fields, append(target, path[i:]...))
+		default:
+			return fmt.Errorf("unknown reference: %s, %s has no nested values", path, path[:i])
+		}
+	}
+
+	return nil
+}
+
+// lookupPath returns a value at the path of a generated record or nil if there is no such value
+func lookupPath(record *orderedObject, path fieldPath) interface{} {
+	var value interface{} = record
+	for _, segment := range path {
+		switch current := value.(type) {
+		case *orderedObject:
+			value, _ = current.get(segment.key)
+		case []interface{}:
+			if segment.index < 0 || segment.index >= len(current) {
+				return nil
+			}
+			value = current[segment.index]
+		default:
+			return nil
+		}
+	}
+
+	return value
+}
+
+// updatePath replaces values at the path of a generated record with the results of update.
+// anyIndex segments update every item of an array
+func updatePath(record *orderedObject, path fieldPath, update func(current interface{}) interface{}) {
+	var assign func(container interface{}, path fieldPath)
+	assign = func(container interface{}, path fieldPath) {
+		segment, last := path[0], len(path) == 1
+		switch current := container.(type) {
+		case *orderedObject:
+			child, ok := current.get(segment.key)
+			switch {
+			case !ok:
+				// an optional field that isn't generated
+			case last:
+				current.set(segment.key, update(child))
+			default:
+				assign(child, path[1:])
+			}
+		case []interface{}:
+			for i := range current {
+				if segment.index != anyIndex && segment.index != i {
+					continue
+				}
+
+				if last {
+					current[i] = update(current[i])
+				} else {
+					assign(current[i], path[1:])
+				}
+			}
+		}
+	}
+
+	assign(record, path)
+}
@@ -0,0 +1,204 @@
+package goson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseFieldPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    fieldPath
+		wantErr bool
+	}{
+		{
+			name:  "positive top-level key",
+			input: "password",
+			want:  fieldPath{{key: "password", index: keySegment}},
+		},
+		{
+			name:  "positive nested keys",
+			input: "user.address.city",
+			want: fieldPath{
+				{key: "user", index: keySegment},
+				{key: "address", index: keySegment},
+				{key: "city", index: keySegment},
+			},
+		},
+		{
+			name:  "positive array indexes",
+			input: "items[0].sizes[1][2]",
+			want: fieldPath{
+				{key: "items", index: keySegment},
+				{index: 0},
+				{key: "sizes", index: keySegment},
+				{index: 1},
+				{index: 2},
+			},
+		},
+		{
+			name:    "empty key",
+			input:   "user..city",
+			wantErr: true,
+		},
+		{
+			name:    "unclosed index",
+			input:   "items[0",
+			wantErr: true,
+		},
+		{
+			name:    "invalid index",
+			input:   "items[-1]",
+			wantErr: true,
+		},
+		{
+			name:    "index without key",
+			input:   "[0]",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFieldPath(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+				assert.Equal(t, tt.input, got.String())
+			}
+		})
+	}
+}
+
+func Test_fieldPath_overlaps(t *testing.T) {
+	items := fieldPath{{key: "items", index: keySegment}}
+	first := items.child(pathSegment{index: 0})
+	any := items.child(pathSegment{index: anyIndex})
+
+	assert.True(t, items.overlaps(first))
+	assert.True(t, first.overlaps(items))
+	assert.True(t, any.overlaps(first))
+	assert.False(t, first.overlaps(items.child(pathSegment{index: 1})))
+	assert.False(t, items.overlaps(fieldPath{{key: "item", index: keySegment}}))
+}
+
+func Test_linkDependencies(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []byte
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "positive chain",
+			input: []byte(`
+				{
+					"a": "_go:_b",
+					"b": "_go:_c",
+					"c": "_go:<5>"
+				}
+			`),
+			want: []string{"b", "a"},
+		},
+		{
+			name: "positive nested paths",
+			input: []byte(`
+				{
+					"city": "_go:_user.address.city",
+					"user": {
+						"address": {"city": "_go:_town"}
+					},
+					"town": "_go:<5>",
+					"first_sku": "_go:_items[0].sku",
+					"items": ["_go:repeat(1, 3)", {"sku": "_go:<5>", "town": "_go:_user.address.city"}]
+				}
+			`),
+			want: []string{"user.address.city", "city", "first_sku", "items[*].town"},
+		},
+		{
+			name: "positive through reference",
+			input: []byte(`
+				{
+					"alias": "_go:_user",
+					"city": "_go:_alias.city",
+					"user": {"city": "_go:<5>"}
+				}
+			`),
+			want: []string{"alias", "city"},
+		},
+		{
+			name: "cycle",
+			input: []byte(`
+				{
+					"a": "_go:_b",
+					"b": "_go:_c",
+					"c": "_go:_a"
+				}
+			`),
+			wantErr: "field a: reference cycle: a -> b -> c -> a",
+		},
+		{
+			name: "self reference",
+			input: []byte(`
+				{
+					"user": {"self": "_go:_user"}
+				}
+			`),
+			wantErr: "field user.self: reference cycle: user.self -> user.self",
+		},
+		{
+			name: "unknown nested key",
+			input: []byte(`
+				{
+					"city": "_go:_user.address.zip",
+					"user": {"address": {"city": "_go:<5>"}}
+				}
+			`),
+			wantErr: "field city: unknown reference: user.address.zip",
+		},
+		{
+			name: "index out of range",
+			input: []byte(`
+				{
+					"sku": "_go:_items[3].sku",
+					"items": ["_go:repeat(1, 3)", {"sku": "_go:<5>"}]
+				}
+			`),
+			wantErr: "field sku: unknown reference: items[3].sku",
+		},
+		{
+			name: "path into a scalar",
+			input: []byte(`
+				{
+					"city": "_go:_user.city",
+					"user": "_go:<5>"
+				}
+			`),
+			wantErr: "field city: unknown reference: user.city, user has no nested values",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := newParserWithCustomKeywords(nil).Parse(tt.input)
+			require.NoError(t, err)
+
+			got, errs := linkDependencies(fields)
+			if tt.wantErr != "" {
+				require.Len(t, errs, 1)
+				require.EqualError(t, errs[0], tt.wantErr)
+				return
+			}
+
+			require.Empty(t, errs)
+			locations := make([]string, len(got))
+			for i, link := range got {
+				locations[i] = link.location.String()
+			}
+			assert.Equal(t, tt.want, locations)
+		})
+	}
+}
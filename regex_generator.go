@@ -0,0 +1,208 @@
+package goson
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"regexp/syntax"
+	"unicode"
+	"unicode/utf8"
+)
+
+// regexDefaultMaxRepeat limits unbounded repetitions: *, + and {n,} repeat
+// at most regexDefaultMaxRepeat times over their minimum
+const regexDefaultMaxRepeat = 10
+
+// regexPrintable is used for "." and for huge classes like [^a] or \D,
+// so they produce printable ASCII instead of arbitrary code points
+var regexPrintable = []rune{' ', '~'}
+
+// regexHugeClass is a number of runes starting from which a class is narrowed to regexPrintable
+const regexHugeClass = 256
+
+// regexGenerator generates strings matching a regular expression in Go regexp/syntax.
+// Anchors and word boundaries are ignored, so a generated string
+// matches the whole expression: ^[A-Z]{2}-\d{4}$ gives values like "QX-0481".
+type regexGenerator struct {
+	re        *syntax.Regexp
+	maxRepeat int
+}
+
+// Generate walks the syntax tree picking random branches, repetitions and characters
+func (regexGenerator *regexGenerator) Generate(rnd *rand.Rand) string {
+	return string(regexGenerator.Append(nil, rnd))
+}
+
+func (regexGenerator *regexGenerator) Append(dst []byte, rnd *rand.Rand) []byte {
+	return regexGenerator.generate(rnd, dst, regexGenerator.re)
+}
+
+func (regexGenerator *regexGenerator) generate(rnd *rand.Rand, dst []byte, re *syntax.Regexp) []byte {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, char := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 && rnd.Intn(2) == 1 {
+				char = unicode.SimpleFold(char)
+			}
+			dst = utf8.AppendRune(dst, char)
+		}
+	case syntax.OpCharClass:
+		dst = utf8.AppendRune(dst, randomClassRune(rnd, re.Rune))
+	case syntax.OpAnyCharNotNL, syntax.OpAnyChar:
+		dst = utf8.AppendRune(dst, randomClassRune(rnd, regexPrintable))
+	case syntax.OpCapture:
+		dst = regexGenerator.generate(rnd, dst, re.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			dst = regexGenerator.generate(rnd, dst, sub)
+		}
+	case syntax.OpAlternate:
+		dst = regexGenerator.generate(rnd, dst, re.Sub[rnd.Intn(len(re.Sub))])
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		count := regexGenerator.repeatCount(re).pick(rnd)
+		for i := 0; i < count; i++ {
+			dst = regexGenerator.generate(rnd, dst, re.Sub[0])
+		}
+	}
+
+	return dst
+}
+
+// repeatCount returns bounds of a repetition, unbounded ones take up to maxRepeat extra items
+func (regexGenerator *regexGenerator) repeatCount(re *syntax.Regexp) charRange {
+	switch re.Op {
+	case syntax.OpStar:
+		return charRange{0, regexGenerator.maxRepeat}
+	case syntax.OpPlus:
+		return charRange{1, 1 + regexGenerator.maxRepeat}
+	case syntax.OpQuest:
+		return charRange{0, 1}
+	}
+
+	if re.Max == -1 {
+		return charRange{re.Min, re.Min + regexGenerator.maxRepeat}
+	}
+
+	return charRange{re.Min, re.Max}
+}
+
+// randomClassRune picks a rune from a class given as pairs of inclusive ranges
+func randomClassRune(rnd *rand.Rand, ranges []rune) rune {
+	n := rnd.Intn(classSize(ranges))
+	for i := 0; i < len(ranges); i += 2 {
+		size := int(ranges[i+1]-ranges[i]) + 1
+		if n < size {
+			return ranges[i] + rune(n)
+		}
+		n -= size
+	}
+
+	return ranges[len(ranges)-1]
+}
+
+func classSize(ranges []rune) int {
+	var size int
+	for i := 0; i < len(ranges); i += 2 {
+		size += int(ranges[i+1]-ranges[i]) + 1
+	}
+
+	return size
+}
+
+// intersectRanges returns parts of the ranges within a single [lo, hi] bound
+func intersectRanges(ranges, bound []rune) []rune {
+	var res []rune
+	for i := 0; i < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		if lo < bound[0] {
+			lo = bound[0]
+		}
+		if hi > bound[1] {
+			hi = bound[1]
+		}
+		if lo <= hi {
+			res = append(res, lo, hi)
+		}
+	}
+
+	return res
+}
+
+// narrowClasses replaces huge classes with their printable ASCII part, if they have one
+func narrowClasses(re *syntax.Regexp) {
+	if re.Op == syntax.OpCharClass && classSize(re.Rune) > regexHugeClass {
+		if printable := intersectRanges(re.Rune, regexPrintable); len(printable) > 0 {
+			re.Rune = printable
+		}
+	}
+
+	for _, sub := range re.Sub {
+		narrowClasses(sub)
+	}
+}
+
+// checkRegex rejects expressions that can't match anything, e.g. [^\x00-\x{10FFFF}]
+func checkRegex(re *syntax.Regexp) error {
+	if re.Op == syntax.OpNoMatch || re.Op == syntax.OpCharClass && len(re.Rune) == 0 {
+		return errors.New("expression matches nothing")
+	}
+
+	for _, sub := range re.Sub {
+		if err := checkRegex(sub); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newRegexGenerator(expr string, maxRepeat int) (iPatternGenerator, error) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkRegex(re); err != nil {
+		return nil, err
+	}
+	narrowClasses(re)
+
+	return &regexGenerator{
+		re:        re,
+		maxRepeat: maxRepeat,
+	}, nil
+}
+
+// newRegexField handles "regex" keyword: "_go:regex(\"^[A-Z]{2}-\\d{4}$\")",
+// the optional second argument limits unbounded repetitions: "_go:regex(\"a+\", 3)".
+// An expression should be quoted if it starts with "[" or has commas outside of brackets.
+func newRegexField(name string, call *KeywordCall) (ParsedField, error) {
+	if err := call.Arity(1, 2); err != nil {
+		return nil, err
+	}
+
+	expr, err := call.String(0)
+	if err != nil {
+		return nil, err
+	}
+
+	maxRepeat := int64(regexDefaultMaxRepeat)
+	if len(call.Args) == 2 {
+		if maxRepeat, err = call.Int(1); err != nil {
+			return nil, err
+		}
+		if maxRepeat < 0 || maxRepeat > repeatMaxCount {
+			return nil, fmt.Errorf("keyword %s: max repeat must be within [0, %d], got %d", call.Keyword, repeatMaxCount, maxRepeat)
+		}
+	}
+
+	pattern, err := newRegexGenerator(expr, int(maxRepeat))
+	if err != nil {
+		return nil, fmt.Errorf("keyword %s: %v", call.Keyword, err)
+	}
+
+	return &patternField{
+		name:    name,
+		pattern: pattern,
+	}, nil
+}
@@ -0,0 +1,133 @@
+package goson
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_regexGenerator_Generate(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{
+			name: "structured code",
+			expr: `^[A-Z]{2}-\d{4}-[a-z]{3}$`,
+		},
+		{
+			name: "alternation and groups",
+			expr: `^(foo|bar)_(\d+|x{2,3})$`,
+		},
+		{
+			name: "unbounded repetitions",
+			expr: `^a*b+c{2,}$`,
+		},
+		{
+			name: "negated class and any char",
+			expr: `^[^a-z]{5}\..$`,
+		},
+		{
+			name: "case folding",
+			expr: `^(?i)hello$`,
+		},
+		{
+			name: "unicode class",
+			expr: `^[а-я]{3}$`,
+		},
+	}
+	rnd := newTestRand()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testGenerator, err := newRegexGenerator(tt.expr, regexDefaultMaxRepeat)
+			require.NoError(t, err)
+
+			re := regexp.MustCompile(tt.expr)
+			for i := 0; i < 100; i++ {
+				got := testGenerator.Generate(rnd)
+				assert.True(t, utf8.ValidString(got))
+				assert.Regexp(t, re, got)
+			}
+		})
+	}
+}
+
+func Test_regexGenerator_maxRepeat(t *testing.T) {
+	testGenerator, err := newRegexGenerator(`a*b+`, 3)
+	require.NoError(t, err)
+
+	rnd := newTestRand()
+	for i := 0; i < 100; i++ {
+		assert.Regexp(t, `^a{0,3}b{1,4}$`, testGenerator.Generate(rnd))
+	}
+}
+
+func Test_newRegexField(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{
+			name:  "positive quoted",
+			input: `regex("^[A-Z]{2,3}$")`,
+		},
+		{
+			name:  "positive bare word",
+			input: `regex(^\d{4}$)`,
+		},
+		{
+			name:  "positive max repeat",
+			input: `regex("a+", 3)`,
+		},
+		{
+			name:    "invalid expression",
+			input:   `regex("a(b")`,
+			wantErr: true,
+		},
+		{
+			name:    "matches nothing",
+			input:   `regex("[^\\x00-\\x{10FFFF}]")`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid max repeat",
+			input:   `regex("a+", -1)`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid arity",
+			input:   "regex",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call, err := parseKeywordCall(tt.input)
+			require.NoError(t, err)
+			got, err := newRegexField("code", call)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "code", got.Name())
+			}
+		})
+	}
+}
+
+func Test_processor_Generate_regex(t *testing.T) {
+	body := []byte(`{"sku": "_go:regex(\"^[A-Z]{2}-\\\\d{4}$\")"}`)
+	testProcessor, err := New(body, WithSeed(1))
+	require.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		var got map[string]string
+		require.NoError(t, json.Unmarshal(testProcessor.Generate(), &got))
+		assert.Regexp(t, `^[A-Z]{2}-\d{4}$`, got["sku"])
+	}
+}
@@ -0,0 +1,165 @@
+package goson
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+)
+
+// Format is a framing of the records written by Processor.GenerateTo
+type Format int
+
+const (
+	// FormatNDJSON writes newline-delimited JSON: one record per line
+	FormatNDJSON Format = iota
+	// FormatJSONArray writes all records as elements of one JSON array
+	FormatJSONArray
+)
+
+const defaultStreamBufferSize = 64 << 10
+
+// StreamOption configures Processor.GenerateTo
+type StreamOption func(*streamOptions)
+
+type streamOptions struct {
+	format     Format
+	bufferSize int
+	ordered    bool
+}
+
+// WithFormat sets a framing of the records, FormatNDJSON by default
+func WithFormat(format Format) StreamOption {
+	return func(options *streamOptions) {
+		options.format = format
+	}
+}
+
+// WithBufferSize sets a size of the write buffer, 64KB by default
+func WithBufferSize(size int) StreamOption {
+	return func(options *streamOptions) {
+		options.bufferSize = size
+	}
+}
+
+// WithOrdered makes Processor.GenerateParallel write records in a deterministic order.
+// GenerateTo always writes records in the order they are generated
+func WithOrdered() StreamOption {
+	return func(options *streamOptions) {
+		options.ordered = true
+	}
+}
+
+func newStreamOptions(opts []StreamOption) *streamOptions {
+	options := &streamOptions{
+		format:     FormatNDJSON,
+		bufferSize: defaultStreamBufferSize,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+// recordWriter frames records according to a Format
+type recordWriter struct {
+	w       *bufio.Writer
+	format  Format
+	written int
+}
+
+func (recordWriter *recordWriter) begin() error {
+	if recordWriter.format == FormatJSONArray {
+		_, err := recordWriter.w.WriteString("[\n")
+		return err
+	}
+
+	return nil
+}
+
+func (recordWriter *recordWriter) write(record []byte) error {
+	if recordWriter.format == FormatJSONArray && recordWriter.written > 0 {
+		if _, err := recordWriter.w.WriteString(",\n"); err != nil {
+			return err
+		}
+	}
+
+	if _, err := recordWriter.w.Write(record); err != nil {
+		return err
+	}
+	recordWriter.written++
+
+	if recordWriter.format == FormatNDJSON {
+		return recordWriter.w.WriteByte('\n')
+	}
+
+	return nil
+}
+
+func (recordWriter *recordWriter) end() error {
+	if recordWriter.format == FormatJSONArray {
+		if recordWriter.written > 0 {
+			if err := recordWriter.w.WriteByte('\n'); err != nil {
+				return err
+			}
+		}
+
+		if _, err := recordWriter.w.WriteString("]\n"); err != nil {
+			return err
+		}
+	}
+
+	return recordWriter.w.Flush()
+}
+
+func newRecordWriter(w io.Writer, options *streamOptions) (*recordWriter, error) {
+	if options.format != FormatNDJSON && options.format != FormatJSONArray {
+		return nil, fmt.Errorf("unknown stream format: %d", options.format)
+	}
+
+	return &recordWriter{
+		w:      bufio.NewWriterSize(w, options.bufferSize),
+		format: options.format,
+	}, nil
+}
+
+// GenerateTo generates n records and writes them into w, reusing one buffer for all of them.
+// It stops when ctx is canceled and returns ctx.Err(); the records generated so far are flushed,
+// but a JSON array is left unclosed
+func (processor *processor) GenerateTo(ctx context.Context, w io.Writer, n int, opts ...StreamOption) error {
+	if n < 0 {
+		return fmt.Errorf("number of records must not be negative: %d", n)
+	}
+
+	writer, err := newRecordWriter(w, newStreamOptions(opts))
+	if err != nil {
+		return err
+	}
+
+	if err := writer.begin(); err != nil {
+		return err
+	}
+
+	var record []byte
+	for i := 0; i < n; i++ {
+		if err := ctx.Err(); err != nil {
+			if flushErr := writer.w.Flush(); flushErr != nil {
+				return flushErr
+			}
+
+			return err
+		}
+
+		record, err = processor.appendRecord(record[:0], processor.rand)
+		if err != nil {
+			return err
+		}
+
+		if err := writer.write(record); err != nil {
+			return err
+		}
+	}
+
+	return writer.end()
+}
@@ -0,0 +1,92 @@
+package goson
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testStreamBody = []byte(`
+	{
+		"email": "_go:<5/1>@test.com",
+		"password": "_go:<5/2/1>",
+		"confirm_password": "_go:_password"
+	}
+`)
+
+func Test_processor_GenerateTo_ndjson(t *testing.T) {
+	testProcessor, err := New(testStreamBody, WithSeed(1))
+	require.NoError(t, err)
+	replayed, err := New(testStreamBody, WithSeed(1))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, testProcessor.GenerateTo(context.Background(), &buf, 100))
+
+	scanner := bufio.NewScanner(&buf)
+	var lines int
+	for scanner.Scan() {
+		assert.Equal(t, replayed.Generate(), scanner.Bytes())
+		lines++
+	}
+	assert.Equal(t, 100, lines)
+}
+
+func Test_processor_GenerateTo_jsonArray(t *testing.T) {
+	testProcessor, err := New(testStreamBody)
+	require.NoError(t, err)
+
+	for _, n := range []int{0, 1, 100} {
+		var buf bytes.Buffer
+		require.NoError(t, testProcessor.GenerateTo(context.Background(), &buf, n, WithFormat(FormatJSONArray), WithBufferSize(16)))
+
+		var got []map[string]string
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+		require.Len(t, got, n)
+		for _, record := range got {
+			assert.Equal(t, record["password"], record["confirm_password"])
+		}
+	}
+}
+
+func Test_processor_GenerateTo_errors(t *testing.T) {
+	testProcessor, err := New(testStreamBody)
+	require.NoError(t, err)
+
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		var buf bytes.Buffer
+		err := testProcessor.GenerateTo(ctx, &buf, 10)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, buf.Bytes())
+	})
+
+	t.Run("negative number of records", func(t *testing.T) {
+		assert.Error(t, testProcessor.GenerateTo(context.Background(), &bytes.Buffer{}, -1))
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		assert.Error(t, testProcessor.GenerateTo(context.Background(), &bytes.Buffer{}, 1, WithFormat(Format(42))))
+	})
+
+	t.Run("writer error", func(t *testing.T) {
+		err := testProcessor.GenerateTo(context.Background(), testFailingWriter{}, 10)
+		assert.ErrorIs(t, err, errTestWrite)
+	})
+}
+
+var errTestWrite = errors.New("write failed")
+
+type testFailingWriter struct{}
+
+func (testFailingWriter) Write([]byte) (int, error) {
+	return 0, errTestWrite
+}
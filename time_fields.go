@@ -0,0 +1,404 @@
+package goson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timestampField generates the current time or a random instant within a window.
+// Instants are written as unix epochs in a unit, or as strings in a layout and a location
+type timestampField struct {
+	name     string
+	unit     time.Duration  // a unit of epochs, 0 for formatted timestamps
+	layout   string         // a layout of formatted timestamps
+	location *time.Location // a location of formatted timestamps, nil for UTC
+	window   *timeWindow    // bounds of random instants, nil for the current time
+	clock    Clock          // a source of the current time, nil for the system one
+}
+
+func (timestampField *timestampField) Name() string {
+	return timestampField.name
+}
+
+func (timestampField *timestampField) Value() interface{} {
+	return timestampField.valueWith(fallbackRand)
+}
+
+// valueWith returns an errorValue when an instant has no epoch in the unit, so the record fails to marshal
+func (timestampField *timestampField) valueWith(rnd *rand.Rand) interface{} {
+	instant := timestampField.instant(rnd)
+	if err := timestampField.checkEpoch(instant); err != nil {
+		return errorValue{err: err}
+	}
+
+	if timestampField.unit != 0 {
+		return timestampField.epoch(instant)
+	}
+
+	return instant.Format(timestampField.layout)
+}
+
+func (timestampField *timestampField) appendJSON(enc *encoder) {
+	instant := timestampField.instant(enc.rnd)
+	if err := timestampField.checkEpoch(instant); err != nil {
+		enc.fail(err)
+		return
+	}
+
+	if timestampField.unit != 0 {
+		enc.buf = strconv.AppendInt(enc.buf, timestampField.epoch(instant), 10)
+		return
+	}
+
+	enc.scratch = instant.AppendFormat(enc.scratch[:0], timestampField.layout)
+	enc.appendScratch()
+}
+
+// instant picks the current time or a random instant of the window in the field's location
+func (timestampField *timestampField) instant(rnd *rand.Rand) time.Time {
+	location := timestampField.location
+	if location == nil {
+		location = time.UTC
+	}
+
+	now := clockNow(timestampField.clock)
+	if timestampField.window == nil {
+		return now.In(location)
+	}
+
+	from, to := timestampField.window.from.at(now), timestampField.window.to.at(now)
+	if to.Before(from) {
+		from, to = to, from
+	}
+
+	return randomInstant(rnd, from, to).In(location)
+}
+
+// checkEpoch fails for instants out of the range of unix nanoseconds when the unit is ns,
+// e.g. a relative bound of a clock that went past 2262
+func (timestampField *timestampField) checkEpoch(instant time.Time) error {
+	if timestampField.unit != time.Nanosecond || inNanoRange(instant) {
+		return nil
+	}
+
+	return fmt.Errorf("field %s: instant %s is out of the range of unix nanoseconds",
+		timestampField.name, instant.Format(time.RFC3339))
+}
+
+// epoch returns a unix epoch of an instant in the field's unit
+func (timestampField *timestampField) epoch(instant time.Time) int64 {
+	switch timestampField.unit {
+	case time.Second:
+		return instant.Unix()
+	case time.Millisecond:
+		return instant.UnixMilli()
+	case time.Microsecond:
+		return instant.UnixMicro()
+	default:
+		return instant.UnixNano()
+	}
+}
+
+// randomInstant picks an instant within [from, to]. Windows wider than int64 nanoseconds, about 292 years,
+// draw seconds and nanoseconds separately, so bounds aren't limited to the years of unix nanoseconds
+func randomInstant(rnd *rand.Rand, from, to time.Time) time.Time {
+	// Sub saturates to math.MaxInt64 for wider windows
+	if span := to.Sub(from); span < math.MaxInt64 {
+		return from.Add(time.Duration(randomInt64(rnd, 0, int64(span))))
+	}
+
+	instant := time.Unix(randomInt64(rnd, from.Unix(), to.Unix()), rnd.Int63n(int64(time.Second)))
+	switch {
+	case instant.Before(from):
+		return from
+	case instant.After(to):
+		return to
+	}
+
+	return instant
+}
+
+// inNanoRange reports whether an instant is representable in unix nanoseconds: from 1677 to 2262
+func inNanoRange(instant time.Time) bool {
+	return instant.Equal(time.Unix(0, instant.UnixNano()))
+}
+
+func (timestampField *timestampField) useClock(clock Clock) {
+	timestampField.clock = clock
+}
+
+// timeWindow is a range of instants, bounds are inclusive
+type timeWindow struct {
+	from, to timeBound
+}
+
+// timeBound is an absolute instant or an offset from the current time
+type timeBound struct {
+	relative bool
+	offset   time.Duration
+	instant  time.Time
+}
+
+func (timeBound timeBound) at(now time.Time) time.Time {
+	if timeBound.relative {
+		return now.Add(timeBound.offset)
+	}
+
+	return timeBound.instant
+}
+
+var timestampUnits = map[string]time.Duration{
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"ns": time.Nanosecond,
+}
+
+// timestampLayouts are named formats of the timestamp keyword
+var timestampLayouts = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"rfc1123":     time.RFC1123,
+	"rfc1123z":    time.RFC1123Z,
+	"date":        "2006-01-02",
+	"datetime":    "2006-01-02 15:04:05",
+	"time":        "15:04:05",
+	"kitchen":     time.Kitchen,
+}
+
+// timeBoundLayouts are layouts of absolute bounds, bounds without an offset are taken in the field's location
+var timeBoundLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// timeNow is a bound of a window that ends or starts at the current time
+const timeNow = "now"
+
+// newTimestampField handles "timestamp" keyword: "_go:timestamp(format[, from, to][, zone])".
+// Format is a unit of unix epochs: s (default), ms, us or ns, a named format: rfc3339, date, datetime...,
+// or a Go layout like "2006-01-02 15:04". Zone is an IANA name like Europe/Berlin or an offset like +03:00.
+// Without bounds the current time is written, otherwise a random instant within [from, to].
+// A bound is now, an offset from now like -30d, +12h or -1w2d, an absolute date or time like 2020-01-01,
+// or a unix epoch in seconds. For example "_go:timestamp(ms)" or "_go:timestamp(rfc3339, -30d, now, UTC)"
+func newTimestampField(name string, call *KeywordCall) (ParsedField, error) {
+	if err := call.Arity(0, 4); err != nil {
+		return nil, err
+	}
+
+	field := &timestampField{
+		name: name,
+		unit: time.Second,
+	}
+	if len(call.Args) > 0 {
+		format, err := call.String(0)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := field.setFormat(format); err != nil {
+			return nil, fmt.Errorf("keyword %s: %v", call.Keyword, err)
+		}
+	}
+
+	if n := len(call.Args); n == 2 || n == 4 {
+		zone, err := call.String(n - 1)
+		if err != nil {
+			return nil, err
+		}
+
+		if field.location, err = parseLocation(zone); err != nil {
+			return nil, fmt.Errorf("keyword %s: %v", call.Keyword, err)
+		}
+	}
+
+	if len(call.Args) >= 3 {
+		window, err := field.parseWindow(call.Args[1], call.Args[2])
+		if err != nil {
+			return nil, fmt.Errorf("keyword %s: %v", call.Keyword, err)
+		}
+		field.window = window
+	}
+
+	return field, nil
+}
+
+// setFormat sets a unit, a named format or a layout
+func (timestampField *timestampField) setFormat(format string) error {
+	if unit, ok := timestampUnits[format]; ok {
+		timestampField.unit = unit
+		return nil
+	}
+
+	layout, ok := timestampLayouts[format]
+	if !ok {
+		layout = format
+	}
+
+	// a layout must depend on the time, otherwise it's rather a typo of a unit or a name
+	if time.Unix(0, 0).UTC().Format(layout) == time.Unix(1e9+1, 0).UTC().Format(layout) {
+		return fmt.Errorf("unknown format %s", format)
+	}
+
+	timestampField.unit, timestampField.layout = 0, layout
+
+	return nil
+}
+
+func (timestampField *timestampField) parseWindow(rawFrom, rawTo interface{}) (*timeWindow, error) {
+	from, err := timestampField.parseBound(rawFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := timestampField.parseBound(rawTo)
+	if err != nil {
+		return nil, err
+	}
+
+	if from.relative == to.relative && to.at(time.Time{}).Before(from.at(time.Time{})) {
+		return nil, fmt.Errorf("from %v is later than to %v", rawFrom, rawTo)
+	}
+
+	// relative bounds are checked from the current time, instants of other clocks are checked when generated
+	if timestampField.unit == time.Nanosecond {
+		now := time.Now()
+		for _, bound := range []timeBound{from, to} {
+			if instant := bound.at(now); !inNanoRange(instant) {
+				return nil, fmt.Errorf("bound %s is out of the range of unix nanoseconds", instant.Format(time.RFC3339))
+			}
+		}
+	}
+
+	return &timeWindow{
+		from: from,
+		to:   to,
+	}, nil
+}
+
+func (timestampField *timestampField) parseBound(raw interface{}) (timeBound, error) {
+	switch raw := raw.(type) {
+	case json.Number:
+		seconds, err := raw.Int64()
+		if err != nil {
+			return timeBound{}, fmt.Errorf("invalid epoch bound %s", raw)
+		}
+
+		return timeBound{instant: time.Unix(seconds, 0)}, nil
+	case string:
+		if raw == timeNow {
+			return timeBound{relative: true}, nil
+		}
+
+		if strings.HasPrefix(raw, "-") || strings.HasPrefix(raw, "+") {
+			offset, err := parseTimeOffset(raw)
+			if err != nil {
+				return timeBound{}, err
+			}
+
+			return timeBound{relative: true, offset: offset}, nil
+		}
+
+		location := timestampField.location
+		if location == nil {
+			location = time.UTC
+		}
+
+		for _, layout := range timeBoundLayouts {
+			if instant, err := time.ParseInLocation(layout, raw, location); err == nil {
+				return timeBound{instant: instant}, nil
+			}
+		}
+
+		return timeBound{}, fmt.Errorf("invalid bound %s", raw)
+	default:
+		return timeBound{}, fmt.Errorf("invalid bound %v", raw)
+	}
+}
+
+// timeOffsetUnits are units of offsets that time.ParseDuration doesn't know
+var timeOffsetUnits = map[string]time.Duration{
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// parseTimeOffset parses a signed offset from the current time: -30d, +1w2d or -1h30m
+func parseTimeOffset(raw string) (time.Duration, error) {
+	sign, rest := raw[:1], raw[1:]
+	if rest == "" {
+		return 0, fmt.Errorf("invalid offset %s", raw)
+	}
+
+	var offset time.Duration
+	for rest != "" {
+		digits := len(rest) - len(strings.TrimLeft(rest, "0123456789."))
+		units := len(rest[digits:]) - len(strings.TrimLeft(rest[digits:], "abcdefghijklmnopqrstuvwxyzµ"))
+		if digits == 0 || units == 0 {
+			return 0, fmt.Errorf("invalid offset %s", raw)
+		}
+
+		number, unit := rest[:digits], rest[digits:digits+units]
+		rest = rest[digits+units:]
+
+		var duration time.Duration
+		var err error
+		if size, ok := timeOffsetUnits[unit]; ok {
+			var count float64
+			count, err = strconv.ParseFloat(number, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid offset %s", raw)
+			}
+
+			// float64(math.MaxInt64) rounds up to 2^63, so greater or equal values don't fit
+			if count*float64(size) >= math.MaxInt64 {
+				return 0, fmt.Errorf("offset %s is longer than %v", raw, time.Duration(math.MaxInt64))
+			}
+			duration = time.Duration(count * float64(size))
+		} else {
+			duration, err = time.ParseDuration(number + unit)
+			if err != nil {
+				return 0, fmt.Errorf("invalid offset %s", raw)
+			}
+		}
+
+		if offset > math.MaxInt64-duration {
+			return 0, fmt.Errorf("offset %s is longer than %v", raw, time.Duration(math.MaxInt64))
+		}
+		offset += duration
+	}
+
+	if sign == "-" {
+		offset = -offset
+	}
+
+	return offset, nil
+}
+
+// parseLocation loads a time zone by its IANA name or builds a fixed one from an offset like +03:00
+func parseLocation(zone string) (*time.Location, error) {
+	if strings.HasPrefix(zone, "+") || strings.HasPrefix(zone, "-") {
+		offset, err := time.Parse("-07:00", zone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid zone offset %s", zone)
+		}
+
+		_, seconds := offset.Zone()
+
+		return time.FixedZone(zone, seconds), nil
+	}
+
+	location, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, errors.New("unknown time zone " + zone)
+	}
+
+	return location, nil
+}
@@ -0,0 +1,278 @@
+package goson
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newTimestampField(t *testing.T) {
+	plusThree := time.FixedZone("+03:00", 3*60*60)
+	tests := []struct {
+		name    string
+		input   string
+		want    *timestampField
+		wantErr bool
+	}{
+		{
+			name:  "default unit",
+			input: "timestamp",
+			want:  &timestampField{name: "time", unit: time.Second},
+		},
+		{
+			name:  "unit",
+			input: "timestamp(us)",
+			want:  &timestampField{name: "time", unit: time.Microsecond},
+		},
+		{
+			name:  "named format",
+			input: "timestamp:rfc3339",
+			want:  &timestampField{name: "time", layout: time.RFC3339},
+		},
+		{
+			name:  "layout and zone",
+			input: `timestamp("02.01.2006 15:04", +03:00)`,
+			want:  &timestampField{name: "time", layout: "02.01.2006 15:04", location: plusThree},
+		},
+		{
+			name:  "relative window",
+			input: "timestamp(ms, -30d, now)",
+			want: &timestampField{
+				name: "time",
+				unit: time.Millisecond,
+				window: &timeWindow{
+					from: timeBound{relative: true, offset: -30 * 24 * time.Hour},
+					to:   timeBound{relative: true},
+				},
+			},
+		},
+		{
+			name:  "absolute window in a zone",
+			input: "timestamp(date, 2020-01-01, 2020-12-31T23:59:59Z, +03:00)",
+			want: &timestampField{
+				name:     "time",
+				layout:   "2006-01-02",
+				location: plusThree,
+				window: &timeWindow{
+					from: timeBound{instant: time.Date(2020, 1, 1, 0, 0, 0, 0, plusThree)},
+					to:   timeBound{instant: time.Date(2020, 12, 31, 23, 59, 59, 0, time.UTC)},
+				},
+			},
+		},
+		{
+			name:  "epoch window",
+			input: "timestamp(s, 0, 1000)",
+			want: &timestampField{
+				name: "time",
+				unit: time.Second,
+				window: &timeWindow{
+					from: timeBound{instant: time.Unix(0, 0)},
+					to:   timeBound{instant: time.Unix(1000, 0)},
+				},
+			},
+		},
+		{
+			name:    "unknown format",
+			input:   "timestamp(h)",
+			wantErr: true,
+		},
+		{
+			name:    "unknown zone",
+			input:   "timestamp(rfc3339, Mars/Olympus)",
+			wantErr: true,
+		},
+		{
+			name:    "reversed window",
+			input:   "timestamp(s, now, -1d)",
+			wantErr: true,
+		},
+		{
+			name:    "invalid bound",
+			input:   "timestamp(s, yesterday, now)",
+			wantErr: true,
+		},
+		{
+			name:    "too many arguments",
+			input:   "timestamp(s, -1d, now, UTC, UTC)",
+			wantErr: true,
+		},
+		{
+			name:    "bound out of nanoseconds",
+			input:   "timestamp(ns, 1000-01-01, 2000-01-01)",
+			wantErr: true,
+		},
+		{
+			name:    "relative bound out of nanoseconds",
+			input:   "timestamp(ns, now, +13000w)",
+			wantErr: true,
+		},
+		{
+			name:    "negative relative bound out of nanoseconds",
+			input:   "timestamp(ns, -19000w, now)",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			call, err := parseKeywordCall(tt.input)
+			require.NoError(t, err)
+
+			got, err := newTimestampField("time", call)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			field := got.(*timestampField)
+			if tt.want.window != nil {
+				require.NotNil(t, field.window)
+				assert.True(t, tt.want.window.from.instant.Equal(field.window.from.instant))
+				assert.True(t, tt.want.window.to.instant.Equal(field.window.to.instant))
+				tt.want.window.from.instant, tt.want.window.to.instant = field.window.from.instant, field.window.to.instant
+			}
+			assert.Equal(t, tt.want, field)
+		})
+	}
+}
+
+func Test_parseTimeOffset(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{input: "-30d", want: -30 * 24 * time.Hour},
+		{input: "+1w2d", want: 9 * 24 * time.Hour},
+		{input: "-1h30m", want: -90 * time.Minute},
+		{input: "+0.5d", want: 12 * time.Hour},
+		{input: "-", wantErr: true},
+		{input: "-d", wantErr: true},
+		{input: "-30", wantErr: true},
+		{input: "+1y", wantErr: true},
+		{input: "-19000w", wantErr: true},
+		{input: "+15000w2000d", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseTimeOffset(tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_timestampField_valueWith(t *testing.T) {
+	newField := func(input string) *timestampField {
+		call, err := parseKeywordCall(input)
+		require.NoError(t, err)
+
+		field, err := newTimestampField("time", call)
+		require.NoError(t, err)
+
+		return field.(*timestampField)
+	}
+	rnd := rand.New(rand.NewSource(1))
+
+	t.Run("epoch units", func(t *testing.T) {
+		before := time.Now()
+		seconds := newField("timestamp").valueWith(rnd).(int64)
+		millis := newField("timestamp(ms)").valueWith(rnd).(int64)
+		after := time.Now()
+
+		assert.True(t, seconds >= before.Unix() && seconds <= after.Unix())
+		assert.True(t, millis >= before.UnixMilli() && millis <= after.UnixMilli())
+	})
+
+	t.Run("relative window", func(t *testing.T) {
+		field := newField("timestamp(rfc3339nano, -1w, -1d)")
+		for i := 0; i < 100; i++ {
+			got, err := time.Parse(time.RFC3339Nano, field.valueWith(rnd).(string))
+			require.NoError(t, err)
+
+			age := time.Since(got)
+			assert.True(t, age >= 24*time.Hour && age <= 7*24*time.Hour+time.Minute, age)
+		}
+	})
+
+	t.Run("absolute window in a zone", func(t *testing.T) {
+		field := newField(`timestamp("2006-01-02T15:04:05-07:00", 2020-01-01, 2020-01-02, +03:00)`)
+		for i := 0; i < 100; i++ {
+			got, err := time.Parse(time.RFC3339, field.valueWith(rnd).(string))
+			require.NoError(t, err)
+
+			_, offset := got.Zone()
+			assert.Equal(t, 3*60*60, offset)
+			assert.Equal(t, 2020, got.Year())
+			assert.Equal(t, time.January, got.Month())
+			assert.Contains(t, []int{1, 2}, got.Day())
+		}
+	})
+
+	t.Run("window wider than nanoseconds", func(t *testing.T) {
+		from, to := time.Date(1000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC)
+		formatted := newField("timestamp(rfc3339nano, 1000-01-01, 3000-01-01)")
+		seconds := newField("timestamp(s, 1000-01-01, 3000-01-01)")
+		var early, late bool
+		for i := 0; i < 100; i++ {
+			got, err := time.Parse(time.RFC3339Nano, formatted.valueWith(rnd).(string))
+			require.NoError(t, err)
+			assert.False(t, got.Before(from) || got.After(to), got)
+			early, late = early || got.Year() < 1600, late || got.Year() > 2300
+
+			epoch := seconds.valueWith(rnd).(int64)
+			assert.True(t, epoch >= from.Unix() && epoch <= to.Unix(), epoch)
+		}
+		assert.True(t, early && late)
+	})
+
+	t.Run("date", func(t *testing.T) {
+		assert.Equal(t, "2021-06-15", newField("timestamp(date, 2021-06-15, 2021-06-15)").valueWith(rnd))
+	})
+}
+
+func Test_processor_GenerateRecord_timestampOutOfNanoseconds(t *testing.T) {
+	body := []byte(`{"at": "_go:timestamp(ns, now, +30d)"}`)
+	for _, compiled := range []bool{true, false} {
+		testProcessor, err := New(body, WithClock(NewFixedClock(time.Date(2262, 4, 1, 0, 0, 0, 0, time.UTC))), WithSeed(1))
+		require.NoError(t, err)
+		if !compiled {
+			testProcessor.(*processor).program = nil
+		}
+
+		_, err = testProcessor.GenerateRecord()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "out of the range of unix nanoseconds")
+	}
+}
+
+func Test_processor_Generate_timestamp(t *testing.T) {
+	body := []byte(`
+		{
+			"created": "_go:timestamp(ms, 1600000000, 1700000000)",
+			"updated": "_go:timestamp(rfc3339, 2020-01-01, 2021-01-01)",
+			"day": "_go:day ${timestamp(date, 2020-01-01, 2020-12-31)}",
+			"era": "_go:timestamp(rfc3339, 1000-01-01, 3000-01-01)"
+		}
+	`)
+
+	compiled, err := New(body, WithSeed(4))
+	require.NoError(t, err)
+	require.NotNil(t, compiled.(*processor).program)
+
+	reference, err := New(body, WithSeed(4))
+	require.NoError(t, err)
+	reference.(*processor).program = nil
+
+	for i := 0; i < 50; i++ {
+		assert.Equal(t, string(reference.Generate()), string(compiled.Generate()))
+	}
+}
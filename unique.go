@@ -0,0 +1,201 @@
+package goson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+)
+
+// uniqueKeyword wraps a directive whose values must not repeat: "_go:unique(<3>)" or "_go:unique(int(1, 100), 1000)"
+const uniqueKeyword = "unique"
+
+const (
+	uniqueDefaultRetries = 100
+	uniqueMaxRetries     = 1000000
+)
+
+// uniqueField generates values of a field that it hasn't generated yet during the lifetime of a processor.
+// A taken value is generated again up to retries times. Values are claimed in the order records are generated
+type uniqueField struct {
+	field    ParsedField
+	path     string
+	retries  int
+	capacity float64 // a number of possible values of the field, +Inf when it is unknown
+
+	mu   sync.Mutex
+	seen map[string]struct{} // JSON of the generated values
+}
+
+func (uniqueField *uniqueField) Name() string {
+	return uniqueField.field.Name()
+}
+
+func (uniqueField *uniqueField) Value() interface{} {
+	return uniqueField.valueWith(fallbackRand)
+}
+
+// valueWith returns an errorValue when there is no fresh value, so the record fails to marshal
+func (uniqueField *uniqueField) valueWith(rnd *rand.Rand) interface{} {
+	for attempt := 0; ; attempt++ {
+		value := fieldValue(uniqueField.field, rnd)
+		key, err := json.Marshal(value)
+		if err != nil {
+			return errorValue{err: err}
+		}
+
+		fresh, err := uniqueField.claim(key, attempt)
+		switch {
+		case err != nil:
+			return errorValue{err: err}
+		case fresh:
+			return value
+		}
+	}
+}
+
+func (uniqueField *uniqueField) appendJSON(enc *encoder) {
+	start := len(enc.buf)
+	for attempt := 0; ; attempt++ {
+		enc.appendField(uniqueField.field)
+		if enc.err != nil {
+			return
+		}
+
+		fresh, err := uniqueField.claim(enc.buf[start:], attempt)
+		switch {
+		case err != nil:
+			enc.fail(err)
+			return
+		case fresh:
+			return
+		}
+		enc.buf = enc.buf[:start]
+	}
+}
+
+// claim remembers a generated value and reports whether it is fresh.
+// It fails when every possible value is taken or when the attempt was the last one
+func (uniqueField *uniqueField) claim(key []byte, attempt int) (bool, error) {
+	uniqueField.mu.Lock()
+	defer uniqueField.mu.Unlock()
+
+	if _, ok := uniqueField.seen[string(key)]; !ok {
+		uniqueField.seen[string(key)] = struct{}{}
+		return true, nil
+	}
+
+	if float64(len(uniqueField.seen)) >= uniqueField.capacity || attempt >= uniqueField.retries {
+		return false, &UniqueError{
+			Path:      uniqueField.path,
+			Generated: len(uniqueField.seen),
+			Capacity:  uniqueField.capacity,
+			Retries:   uniqueField.retries,
+		}
+	}
+
+	return false, nil
+}
+
+func newUniqueField(field ParsedField, path string, retries int) ParsedField {
+	return &uniqueField{
+		field:    field,
+		path:     path,
+		retries:  retries,
+		capacity: fieldCapacity(field),
+		seen:     make(map[string]struct{}),
+	}
+}
+
+// errorValue is a value of a field that failed to generate, it fails the marshaling of a record
+type errorValue struct {
+	err error
+}
+
+func (errorValue errorValue) MarshalJSON() ([]byte, error) {
+	return nil, errorValue.err
+}
+
+// countable is a pattern generator that knows a number of its distinct values
+type countable interface {
+	capacity() float64
+}
+
+// fieldCapacity returns a number of distinct values of a field, or an upper bound of it, +Inf when it is unknown
+func fieldCapacity(field ParsedField) float64 {
+	switch field := field.(type) {
+	case *staticField:
+		return 1
+	case *intField:
+		return float64(uint64(field.max-field.min)) + 1
+	case *decimalField:
+		return float64(uint64(field.max-field.min)) + 1
+	case *oneOfField:
+		distinct := make(map[string]bool, len(field.encoded))
+		for i, encoded := range field.encoded {
+			if field.weight(i) > 0 {
+				distinct[string(encoded)] = true
+			}
+		}
+
+		return float64(len(distinct))
+	case *patternField:
+		if pattern, ok := field.pattern.(countable); ok {
+			return pattern.capacity()
+		}
+	case *templateField:
+		total := 1.0
+		for _, piece := range field.pieces {
+			total *= fieldCapacity(piece)
+		}
+
+		return total
+	}
+
+	return math.Inf(1)
+}
+
+// parseUniqueField parses a unique directive, its first argument is a directive without the prefix
+func (parser *parser) parseUniqueField(key, value string) (ParsedField, error) {
+	call, err := parseKeywordCall(value)
+	if err != nil {
+		return nil, parser.syntaxError(err)
+	}
+
+	if err := call.Arity(1, 2); err != nil {
+		return nil, parser.keywordError(uniqueKeyword, err)
+	}
+
+	directive, err := call.String(0)
+	if err != nil {
+		return nil, parser.keywordError(uniqueKeyword, err)
+	}
+
+	retries := int64(uniqueDefaultRetries)
+	if len(call.Args) == 2 {
+		if retries, err = call.Int(1); err != nil {
+			return nil, parser.keywordError(uniqueKeyword, err)
+		}
+
+		if retries < 0 || retries > uniqueMaxRetries {
+			return nil, parser.keywordError(uniqueKeyword, fmt.Errorf(
+				"keyword %s: retries must be within [0, %d]", uniqueKeyword, uniqueMaxRetries,
+			))
+		}
+	}
+
+	field, err := parser.parseStringField(key, parser.prefix+directive)
+	if err != nil {
+		return nil, err
+	}
+
+	if field, ok := field.(dependentField); ok && len(field.dependencies()) > 0 {
+		return nil, parser.keywordError(uniqueKeyword, errors.New("unique values can't depend on other fields"))
+	}
+
+	parser.stateful = true
+
+	return newUniqueField(field, parser.path.String(), int(retries)), nil
+}
@@ -0,0 +1,147 @@
+package goson
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_fieldCapacity(t *testing.T) {
+	tests := []struct {
+		name      string
+		directive string
+		want      float64
+	}{
+		{name: "letters", directive: "_go:<3>", want: 52 * 52 * 52},
+		{name: "shuffled classes", directive: "_go:<2/1>", want: 52 * 52 * 10 * 3},
+		{name: "count range", directive: "_go:<0-2:digit>", want: 1 + 10 + 100},
+		{name: "segments", directive: "_go:<1:digit>-<1:[ab]>", want: 20},
+		{name: "int", directive: "_go:int(-5, 5)", want: 11},
+		{name: "decimal", directive: "_go:decimal(0, 1, 2)", want: 101},
+		{name: "oneof", directive: "_go:oneof(a, b, a, c, weights(1, 1, 1, 0))", want: 2},
+		{name: "template", directive: "_go:${int(1, 3)}-<1:digit>", want: 30},
+		{name: "uuid", directive: "_go:uuid", want: math.Inf(1)},
+		{name: "regex", directive: `_go:regex("[ab]")`, want: math.Inf(1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testParser := getTestParserStruct()
+			field, err := testParser.parseStringField("code", tt.directive)
+			require.NoError(t, err)
+
+			assert.InEpsilon(t, tt.want, fieldCapacity(field), 1e-9)
+		})
+	}
+}
+
+func Test_parser_parseUniqueField(t *testing.T) {
+	tests := []struct {
+		name        string
+		directive   string
+		wantRetries int
+		wantErr     bool
+	}{
+		{name: "pattern", directive: "_go:unique(<3>)", wantRetries: uniqueDefaultRetries},
+		{name: "keyword with retries", directive: "_go:unique(int(1, 100), 1000)", wantRetries: 1000},
+		{name: "template", directive: `_go:unique("ORD-${int(1, 9)}-<2:digit>")`, wantRetries: uniqueDefaultRetries},
+		{name: "reference", directive: "_go:unique(_password)", wantErr: true},
+		{name: "negative retries", directive: "_go:unique(<3>, -1)", wantErr: true},
+		{name: "no directive", directive: "_go:unique", wantErr: true},
+		{name: "unknown keyword", directive: "_go:unique(nothing)", wantErr: true},
+		{name: "invalid pattern", directive: "_go:unique(<1/2/3/4>)", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testParser := getTestParserStruct()
+			field, err := testParser.parseStringField("code", tt.directive)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.IsType(t, &uniqueField{}, field)
+			assert.Equal(t, "code", field.Name())
+			assert.Equal(t, tt.wantRetries, field.(*uniqueField).retries)
+		})
+	}
+}
+
+func Test_processor_Generate_unique(t *testing.T) {
+	bodies := map[string][]byte{
+		"compiled":   []byte(`{"code": "_go:unique(<1:digit>)", "items": ["_go:repeat(1)", "_go:unique(int(1, 10))"]}`),
+		"references": []byte(`{"code": "_go:unique(<1:digit>)", "items": ["_go:repeat(1)", "_go:unique(int(1, 10))"], "copy": "_go:_code"}`),
+	}
+	for name, body := range bodies {
+		t.Run(name, func(t *testing.T) {
+			testProcessor, err := New(body, WithSeed(1))
+			require.NoError(t, err)
+
+			codes := make(map[string]bool)
+			items := make(map[float64]bool)
+			for i := 0; i < 10; i++ {
+				var record struct {
+					Code  string    `json:"code"`
+					Items []float64 `json:"items"`
+				}
+				require.NoError(t, json.Unmarshal(testProcessor.Generate(), &record))
+				codes[record.Code] = true
+				items[record.Items[0]] = true
+			}
+			assert.Len(t, codes, 10)
+			assert.Len(t, items, 10)
+
+			var buf bytes.Buffer
+			err = testProcessor.GenerateTo(context.Background(), &buf, 1)
+			var uniqueErr *UniqueError
+			require.True(t, errors.As(err, &uniqueErr), err)
+			assert.True(t, uniqueErr.Exhausted())
+			assert.Equal(t, "code", uniqueErr.Path)
+			assert.Equal(t, 10, uniqueErr.Generated)
+			assert.Contains(t, err.Error(), "exhausted")
+
+			record, err := testProcessor.GenerateRecord()
+			assert.Nil(t, record)
+			require.True(t, errors.As(err, &uniqueErr), err)
+			assert.True(t, uniqueErr.Exhausted())
+		})
+	}
+}
+
+func Test_processor_Generate_uniqueRetries(t *testing.T) {
+	// pools share characters, so the capacity of 8 is an upper bound of the 4 possible values
+	testProcessor, err := New([]byte(`{"code": "_go:unique(<1:[ab]/1:[ab]>, 50)"}`), WithSeed(1))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = testProcessor.GenerateTo(context.Background(), &buf, 5)
+	var uniqueErr *UniqueError
+	require.True(t, errors.As(err, &uniqueErr), err)
+	assert.False(t, uniqueErr.Exhausted())
+	assert.Equal(t, 4, uniqueErr.Generated)
+	assert.Equal(t, float64(8), uniqueErr.Capacity)
+	assert.Equal(t, 50, uniqueErr.Retries)
+}
+
+func Test_processor_GenerateParallel_unique(t *testing.T) {
+	testProcessor, err := New([]byte(`{"id": "_go:unique(int(1, 1000), 10000)"}`), WithSeed(1))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, testProcessor.GenerateParallel(context.Background(), &buf, 500, 4))
+
+	ids := make(map[float64]bool)
+	decoder := json.NewDecoder(&buf)
+	for decoder.More() {
+		var record map[string]float64
+		require.NoError(t, decoder.Decode(&record))
+		ids[record["id"]] = true
+	}
+	assert.Len(t, ids, 500)
+}
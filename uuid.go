@@ -0,0 +1,83 @@
+package goson
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"math/rand"
+)
+
+// uuid is an RFC 4122 universally unique identifier
+type uuid [16]byte
+
+// Namespaces for the name-based (version 5) uuids, as defined by RFC 4122 Appendix C.
+var (
+	uuidNamespaceDNS  = uuid{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+	uuidNamespaceURL  = uuid{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+	uuidNamespaceOID  = uuid{0x6b, 0xa7, 0xb8, 0x12, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+	uuidNamespaceX500 = uuid{0x6b, 0xa7, 0xb8, 0x14, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+)
+
+var uuidNamespaces = map[string]uuid{
+	"dns":  uuidNamespaceDNS,
+	"url":  uuidNamespaceURL,
+	"oid":  uuidNamespaceOID,
+	"x500": uuidNamespaceX500,
+}
+
+// String formats uuid in its canonical 8-4-4-4-12 form
+func (u uuid) String() string {
+	return string(u.appendTo(make([]byte, 0, 36)))
+}
+
+// appendTo appends uuid in its canonical form
+func (u uuid) appendTo(dst []byte) []byte {
+	for i, b := range u {
+		if i == 4 || i == 6 || i == 8 || i == 10 {
+			dst = append(dst, '-')
+		}
+		dst = append(dst, hexDigits[b>>4], hexDigits[b&0xf])
+	}
+
+	return dst
+}
+
+// setVersion writes the version into the high nibble of the 7th byte
+// and the RFC 4122 variant into the high bits of the 9th byte
+func (u *uuid) setVersion(version byte) {
+	u[6] = u[6]&0x0f | version<<4
+	u[8] = u[8]&0x3f | 0x80
+}
+
+// newUUIDv4 builds a random uuid
+func newUUIDv4(rnd *rand.Rand) uuid {
+	var u uuid
+	binary.BigEndian.PutUint64(u[:8], rnd.Uint64())
+	binary.BigEndian.PutUint64(u[8:], rnd.Uint64())
+	u.setVersion(4)
+
+	return u
+}
+
+// newUUIDv7 builds a time-ordered uuid: 48 bits of a unix timestamp in milliseconds,
+// 12 bits of a sequence to keep uuids of the same millisecond sorted and 62 random bits
+func newUUIDv7(rnd *rand.Rand, millis int64, seq uint16) uuid {
+	var u uuid
+	binary.BigEndian.PutUint64(u[8:], rnd.Uint64())
+	binary.BigEndian.PutUint64(u[:8], uint64(millis)<<16|uint64(seq&0x0fff))
+	u.setVersion(7)
+
+	return u
+}
+
+// newUUIDv5 builds a name-based uuid from a SHA-1 hash of a namespace and a name
+func newUUIDv5(namespace uuid, name string) uuid {
+	hash := sha1.New()
+	hash.Write(namespace[:])
+	hash.Write([]byte(name))
+
+	var u uuid
+	copy(u[:], hash.Sum(nil))
+	u.setVersion(5)
+
+	return u
+}
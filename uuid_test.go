@@ -0,0 +1,33 @@
+package goson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_uuid_String(t *testing.T) {
+	assert.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", uuidNamespaceDNS.String())
+}
+
+func Test_newUUIDv4(t *testing.T) {
+	rnd := newTestRand()
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		got := newUUIDv4(rnd).String()
+		assert.Regexp(t, "^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$", got)
+		assert.False(t, seen[got])
+		seen[got] = true
+	}
+}
+
+func Test_newUUIDv7(t *testing.T) {
+	got := newUUIDv7(newTestRand(), 0x017f22e279b0, 0x0123).String()
+	assert.Regexp(t, "^017f22e2-79b0-7123-[89ab][0-9a-f]{3}-[0-9a-f]{12}$", got)
+}
+
+func Test_newUUIDv5(t *testing.T) {
+	// reference value from the Python's uuid.uuid5(uuid.NAMESPACE_DNS, "python.org")
+	got := newUUIDv5(uuidNamespaceDNS, "python.org").String()
+	assert.Equal(t, "886313e1-3b8a-5372-9b90-0c9aee199e5d", got)
+}